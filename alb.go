@@ -0,0 +1,76 @@
+package lambdawrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+)
+
+// ALB provides a wrapper to handle an events.ALBTargetGroupRequest from an Application Load Balancer target group, in
+// the same manner as APIGatewayProxy.
+//
+// If multi value headers are enabled on the target group the request will contain multi value headers and query
+// string parameters, the response is returned in the same mode. In single value mode only the first value of any
+// response header is returned. Query string parameters and the path are provided by the ALB as received, they are URL
+// decoded before being added to the HTTPRequest, with the path as received kept as RawPath.
+func ALB(n func(context.Context, []byte) ([]byte, error), opts ...HTTPOption) func(context.Context, events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	return func(ctx context.Context, e events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+		multiValue := e.MultiValueHeaders != nil || e.MultiValueQueryStringParameters != nil
+
+		path, rawPath := httpPath(e.Path, "")
+
+		req := HTTPRequest{
+			Method:  e.HTTPMethod,
+			Path:    path,
+			RawPath: rawPath,
+			Headers: httpHeaders(e.Headers, e.MultiValueHeaders),
+			Query:   albQueryUnescape(httpQuery(e.QueryStringParameters, e.MultiValueQueryStringParameters)),
+		}
+
+		body, err := httpDecodeBody(e.Body, e.IsBase64Encoded)
+		resp, d := httpHandle(ctx, req, body, httpNext(n, err), opts)
+		encoded, isBase64Encoded := httpEncodeBody(d)
+
+		ret := events.ALBTargetGroupResponse{
+			StatusCode:        resp.StatusCode,
+			StatusDescription: fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			Body:              encoded,
+			IsBase64Encoded:   isBase64Encoded,
+		}
+
+		if multiValue {
+			ret.MultiValueHeaders = resp.Headers
+		} else {
+			ret.Headers = make(map[string]string, len(resp.Headers))
+
+			for k := range resp.Headers {
+				ret.Headers[k] = resp.Headers.Get(k)
+			}
+		}
+
+		return ret, nil
+	}
+}
+
+// albQueryUnescape URL decodes query string parameters, any which can not be decoded are left as received.
+func albQueryUnescape(q url.Values) url.Values {
+	ret := url.Values{}
+
+	for k, vs := range q {
+		if uk, err := url.QueryUnescape(k); err == nil {
+			k = uk
+		}
+
+		for _, v := range vs {
+			if uv, err := url.QueryUnescape(v); err == nil {
+				v = uv
+			}
+
+			ret.Add(k, v)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,94 @@
+package lambdawrap
+
+import (
+	"context"
+	"encoding/base64"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pwood/lambdawrap/codec"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestALB(t *testing.T) {
+	type in struct {
+		In string
+	}
+
+	type out struct {
+		Out string
+	}
+
+	t.Run("a single value request is decoded into a domain object, and the response is returned in single value mode", func(t *testing.T) {
+		req := events.ALBTargetGroupRequest{
+			HTTPMethod:            http.MethodPost,
+			Path:                  "/orders",
+			Headers:               map[string]string{"x-request-id": "abc"},
+			QueryStringParameters: map[string]string{"q": "a%20b"},
+			Body:                  base64.StdEncoding.EncodeToString([]byte(`{"In":"message"}`)),
+			IsBase64Encoded:       true,
+		}
+
+		next := func(ctx context.Context, i in) (out, error) {
+			r, ok := HTTPRequestFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/orders", r.Path)
+			assert.Equal(t, "abc", r.Headers.Get("X-Request-Id"))
+			assert.Equal(t, "a b", r.Query.Get("q"))
+
+			resp, _ := HTTPResponseFromContext(ctx)
+			resp.Headers.Add("Vary", "Accept")
+			resp.Headers.Add("Vary", "Cookie")
+
+			return out{Out: i.In}, nil
+		}
+
+		resp, err := ALB(HTTPDomainObject(next, codec.JSON))(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "200 OK", resp.StatusDescription)
+		assert.Equal(t, "application/json", resp.Headers["Content-Type"])
+		assert.Equal(t, "Accept", resp.Headers["Vary"])
+		assert.Nil(t, resp.MultiValueHeaders)
+		assert.Equal(t, `{"Out":"message"}`, resp.Body)
+	})
+
+	t.Run("a multi value request is returned in multi value mode", func(t *testing.T) {
+		req := events.ALBTargetGroupRequest{
+			HTTPMethod:                      http.MethodGet,
+			Path:                            "/",
+			MultiValueHeaders:               map[string][]string{"accept": {"text/plain"}},
+			MultiValueQueryStringParameters: map[string][]string{"a": {"1", "2"}},
+		}
+
+		next := func(ctx context.Context, _ []byte) ([]byte, error) {
+			r, _ := HTTPRequestFromContext(ctx)
+			assert.Equal(t, []string{"1", "2"}, r.Query["a"])
+
+			resp, _ := HTTPResponseFromContext(ctx)
+			resp.Headers.Add("Set-Cookie", "a=1")
+			resp.Headers.Add("Set-Cookie", "b=2")
+
+			return []byte("ok"), nil
+		}
+
+		resp, err := ALB(next)(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a=1", "b=2"}, resp.MultiValueHeaders["Set-Cookie"])
+		assert.Nil(t, resp.Headers)
+		assert.Equal(t, "ok", resp.Body)
+	})
+
+	t.Run("an error from next results in an error status code and description", func(t *testing.T) {
+		next := func(_ context.Context, _ []byte) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		resp, err := ALB(next)(context.TODO(), events.ALBTargetGroupRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "500 Internal Server Error", resp.StatusDescription)
+	})
+}
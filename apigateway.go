@@ -0,0 +1,157 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// APIGatewayProxy provides a wrapper to handle an events.APIGatewayProxyRequest from an API Gateway REST API, calling
+// next with the request body, decoding it first if it is base64 encoded. The []byte output from next becomes the body of
+// the events.APIGatewayProxyResponse.
+//
+// The status code and headers of the response can be changed via HTTPResponseFromContext, the Content-Type is detected
+// from the body if it has not been set. An error from next results in a response with a status code provided by
+// HTTPError, or 500 Internal Server Error if it does not implement HTTPError. Errors are not returned to Lambda, as
+// API Gateway would otherwise replace the response, WithHTTPErrorHook can be used to log or record them.
+//
+// The request is added to the context, and can be extracted with HTTPRequestFromContext. It is recommended you use
+// HTTPDomainObject to decode the body.
+func APIGatewayProxy(n func(context.Context, []byte) ([]byte, error), opts ...HTTPOption) func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	return func(ctx context.Context, e events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		req := HTTPRequest{
+			Method:         e.HTTPMethod,
+			Path:           e.Path,
+			Headers:        httpHeaders(e.Headers, e.MultiValueHeaders),
+			Query:          httpQuery(e.QueryStringParameters, e.MultiValueQueryStringParameters),
+			PathParameters: e.PathParameters,
+		}
+
+		body, err := httpDecodeBody(e.Body, e.IsBase64Encoded)
+		resp, d := httpHandle(ctx, req, body, httpNext(n, err), opts)
+		encoded, isBase64Encoded := httpEncodeBody(d)
+
+		return events.APIGatewayProxyResponse{
+			StatusCode:        resp.StatusCode,
+			MultiValueHeaders: resp.Headers,
+			Body:              encoded,
+			IsBase64Encoded:   isBase64Encoded,
+		}, nil
+	}
+}
+
+// APIGatewayV2HTTP provides a wrapper to handle an events.APIGatewayV2HTTPRequest from an API Gateway HTTP API using
+// payload format version 2.0, in the same manner as APIGatewayProxy. Cookies in the request are provided as a Cookie
+// header, and any Set-Cookie headers in the response are returned as cookies. The stage is removed from the raw
+// path if it is not $default, so the path matches that provided by APIGatewayProxy.
+func APIGatewayV2HTTP(n func(context.Context, []byte) ([]byte, error), opts ...HTTPOption) func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	return func(ctx context.Context, e events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+		path, rawPath := httpPath(e.RawPath, e.RequestContext.Stage)
+
+		req := HTTPRequest{
+			Method:         e.RequestContext.HTTP.Method,
+			Path:           path,
+			RawPath:        rawPath,
+			Headers:        httpV2Headers(e.Headers, e.Cookies),
+			Query:          httpV2Query(e.RawQueryString, e.QueryStringParameters),
+			PathParameters: e.PathParameters,
+		}
+
+		body, err := httpDecodeBody(e.Body, e.IsBase64Encoded)
+		resp, d := httpHandle(ctx, req, body, httpNext(n, err), opts)
+		encoded, isBase64Encoded := httpEncodeBody(d)
+
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode:      resp.StatusCode,
+			Headers:         httpSingleValueHeaders(resp.Headers),
+			Body:            encoded,
+			IsBase64Encoded: isBase64Encoded,
+			Cookies:         resp.Headers.Values("Set-Cookie"),
+		}, nil
+	}
+}
+
+// httpPath normalises the raw path of a request, removing the API Gateway stage if present, so that the path is the
+// same regardless of the event source. The stage is not removed if it is empty or $default. Both the URL decoded path
+// and the raw path are returned, the raw path retains escaped characters such as %2F.
+func httpPath(raw string, stage string) (string, string) {
+	if stage != "" && stage != "$default" {
+		if p := strings.TrimPrefix(raw, "/"+stage); p == "" || (p != raw && p[0] == '/') {
+			raw = "/" + strings.TrimPrefix(p, "/")
+		}
+	}
+
+	return httpPathUnescape(raw), raw
+}
+
+// httpPathUnescape URL decodes a path, or path segment, leaving it unaltered if it contains an invalid escape.
+func httpPathUnescape(raw string) string {
+	if p, err := url.PathUnescape(raw); err == nil {
+		return p
+	}
+
+	return raw
+}
+
+// httpHeaders builds an http.Header from the single and multi value header maps of an API Gateway or ALB request, the
+// multi value headers are preferred if present.
+func httpHeaders(single map[string]string, multi map[string][]string) http.Header {
+	h := http.Header{}
+
+	if len(multi) > 0 {
+		for k, vs := range multi {
+			for _, v := range vs {
+				h.Add(k, v)
+			}
+		}
+	} else {
+		for k, v := range single {
+			h.Add(k, v)
+		}
+	}
+
+	return h
+}
+
+// httpQuery builds url.Values from the single and multi value query string maps of an API Gateway request, the multi
+// value parameters are preferred if present.
+func httpQuery(single map[string]string, multi map[string][]string) url.Values {
+	q := url.Values{}
+
+	if len(multi) > 0 {
+		for k, vs := range multi {
+			for _, v := range vs {
+				q.Add(k, v)
+			}
+		}
+	} else {
+		for k, v := range single {
+			q.Add(k, v)
+		}
+	}
+
+	return q
+}
+
+// httpV2Headers builds an http.Header from the headers and cookies of a payload format version 2.0 request.
+func httpV2Headers(headers map[string]string, cookies []string) http.Header {
+	h := httpHeaders(headers, nil)
+
+	if len(cookies) > 0 {
+		h.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	return h
+}
+
+// httpV2Query builds url.Values from a payload format version 2.0 request, preferring the raw query string as it
+// retains repeated parameters.
+func httpV2Query(raw string, single map[string]string) url.Values {
+	if q, err := url.ParseQuery(raw); err == nil && raw != "" {
+		return q
+	}
+
+	return httpQuery(single, nil)
+}
@@ -0,0 +1,198 @@
+package lambdawrap
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pwood/lambdawrap/codec"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestAPIGatewayProxy(t *testing.T) {
+	type in struct {
+		In string
+	}
+
+	type out struct {
+		Out string
+	}
+
+	t.Run("the request is provided on the context, the body is passed to next and the output is returned as the response", func(t *testing.T) {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:                      "POST",
+			Path:                            "/orders",
+			MultiValueHeaders:               map[string][]string{"x-request-id": {"abc"}},
+			MultiValueQueryStringParameters: map[string][]string{"a": {"1", "2"}},
+			PathParameters:                  map[string]string{"id": "1"},
+			Body:                            `{"In":"message"}`,
+		}
+
+		next := func(ctx context.Context, i in) (out, error) {
+			r, ok := HTTPRequestFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "/orders", r.Path)
+			assert.Equal(t, "abc", r.Headers.Get("X-Request-Id"))
+			assert.Equal(t, []string{"1", "2"}, r.Query["a"])
+			assert.Equal(t, "1", r.PathParameters["id"])
+
+			resp, ok := HTTPResponseFromContext(ctx)
+			assert.True(t, ok)
+			resp.StatusCode = http.StatusCreated
+			resp.Headers.Set("Location", "/orders/1")
+
+			return out{Out: i.In}, nil
+		}
+
+		resp, err := APIGatewayProxy(HTTPDomainObject(next, codec.JSON))(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, []string{"application/json"}, resp.MultiValueHeaders["Content-Type"])
+		assert.Equal(t, []string{"/orders/1"}, resp.MultiValueHeaders["Location"])
+		assert.Equal(t, `{"Out":"message"}`, resp.Body)
+		assert.False(t, resp.IsBase64Encoded)
+	})
+
+	t.Run("a base64 encoded body is decoded and a binary response is base64 encoded", func(t *testing.T) {
+		req := events.APIGatewayProxyRequest{
+			Body:            base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe}),
+			IsBase64Encoded: true,
+		}
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			assert.Equal(t, []byte{0xff, 0xfe}, d)
+			return d, nil
+		}
+
+		resp, err := APIGatewayProxy(next)(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0xff, 0xfe}), resp.Body)
+		assert.True(t, resp.IsBase64Encoded)
+	})
+
+	t.Run("a body which is not valid base64 results in a 400 Bad Request", func(t *testing.T) {
+		req := events.APIGatewayProxyRequest{
+			Body:            "!!",
+			IsBase64Encoded: true,
+		}
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			t.Fatal("next called unexpectedly")
+			return nil, nil
+		}
+
+		resp, err := APIGatewayProxy(next)(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("the body of an error response does not expose the wrapped error", func(t *testing.T) {
+		next := HTTPDomainObject(func(_ context.Context, _ in) (out, error) {
+			return out{}, nil
+		}, codec.JSON)
+
+		resp, err := APIGatewayProxy(next)(context.TODO(), events.APIGatewayProxyRequest{Body: "{invalid"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "Bad Request", resp.Body)
+
+		resp, err = APIGatewayProxy(Err[[]byte](NewHTTPError(http.StatusConflict, errors.New("order 123 locked by user 456"))))(context.TODO(), events.APIGatewayProxyRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "Conflict", resp.Body)
+	})
+
+	t.Run("an HTTPError from next sets the status code and message", func(t *testing.T) {
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return nil, NewHTTPError(http.StatusNotFound, nil)
+		}
+
+		resp, err := APIGatewayProxy(next)(context.TODO(), events.APIGatewayProxyRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "Not Found", resp.Body)
+		assert.Equal(t, []string{"text/plain; charset=utf-8"}, resp.MultiValueHeaders["Content-Type"])
+	})
+
+	t.Run("any other error from next results in a 500 Internal Server Error without exposing the error", func(t *testing.T) {
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		resp, err := APIGatewayProxy(next)(context.TODO(), events.APIGatewayProxyRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "Internal Server Error", resp.Body)
+	})
+}
+
+func TestAPIGatewayV2HTTP(t *testing.T) {
+	t.Run("the request is provided on the context, cookies are mapped and the output is returned as the response", func(t *testing.T) {
+		req := events.APIGatewayV2HTTPRequest{
+			RawPath:        "/orders",
+			RawQueryString: "a=1&a=2",
+			Cookies:        []string{"a=1", "b=2"},
+			Headers:        map[string]string{"x-request-id": "abc"},
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "GET"},
+			},
+		}
+
+		next := func(ctx context.Context, d []byte) ([]byte, error) {
+			r, ok := HTTPRequestFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "GET", r.Method)
+			assert.Equal(t, "/orders", r.Path)
+			assert.Equal(t, "abc", r.Headers.Get("X-Request-Id"))
+			assert.Equal(t, "a=1; b=2", r.Headers.Get("Cookie"))
+			assert.Equal(t, []string{"1", "2"}, r.Query["a"])
+
+			resp, _ := HTTPResponseFromContext(ctx)
+			resp.Headers.Add("Set-Cookie", "c=3")
+			resp.Headers.Add("Vary", "Accept")
+			resp.Headers.Add("Vary", "Cookie")
+
+			return []byte("hello"), nil
+		}
+
+		resp, err := APIGatewayV2HTTP(next)(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello", resp.Body)
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Headers["Content-Type"])
+		assert.Equal(t, "Accept,Cookie", resp.Headers["Vary"])
+		assert.Equal(t, []string{"c=3"}, resp.Cookies)
+		assert.NotContains(t, resp.Headers, "Set-Cookie")
+	})
+}
+
+func TestHTTPPath(t *testing.T) {
+	t.Run("the path is URL decoded and the raw path retained", func(t *testing.T) {
+		path, raw := httpPath("/files/my%20file", "")
+		assert.Equal(t, "/files/my file", path)
+		assert.Equal(t, "/files/my%20file", raw)
+	})
+
+	t.Run("a named stage is removed from the start of the path", func(t *testing.T) {
+		path := func(raw string, stage string) string {
+			_, p := httpPath(raw, stage)
+			return p
+		}
+
+		assert.Equal(t, "/orders", path("/prod/orders", "prod"))
+		assert.Equal(t, "/", path("/prod", "prod"))
+		assert.Equal(t, "/production/orders", path("/production/orders", "prod"))
+		assert.Equal(t, "/$default/orders", path("/$default/orders", "$default"))
+	})
+
+	t.Run("an invalid escape leaves the path unaltered", func(t *testing.T) {
+		path, raw := httpPath("/files/100%", "")
+		assert.Equal(t, "/files/100%", path)
+		assert.Equal(t, "/files/100%", raw)
+	})
+}
@@ -0,0 +1,255 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrBatchSkipped is recorded against records which were not processed, either because the shared context was
+// cancelled after a fatal error, or because an earlier record with the same ordering key failed.
+var ErrBatchSkipped = errors.New("record skipped")
+
+// BatchOption configures how the batch wraps (SQS, SNS, S3Notification, DynamoDBStream, Kinesis and their batch item
+// failure variants) process their records.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	concurrency int
+	ordered     bool
+}
+
+// WithConcurrency permits up to limit records to be processed at the same time. The output of each record is still
+// concatenated in the order the records were received. Wraps which stop at the first error will cancel the context
+// shared by all records when an error occurs, records which have not yet started are skipped, and a BatchError is
+// returned containing every error that occurred.
+//
+// By default, records are processed one at a time.
+func WithConcurrency(limit int) BatchOption {
+	return func(o *batchOptions) {
+		o.concurrency = limit
+	}
+}
+
+// WithOrdered enables ordering safe processing, records which share an ordering key are always processed one at a
+// time in the order they were received, only records with different ordering keys are processed concurrently. If a
+// record fails, later records with the same ordering key are skipped.
+//
+// The ordering key depends upon the wrap: SQS uses the MessageGroupId of FIFO queues (which are always ordered), or the
+// message ID for standard queues which have no ordering, DynamoDBStream uses the keys of the item, Kinesis uses the
+// partition key, S3Notification uses the bucket and object key, and SNS uses the topic ARN.
+func WithOrdered() BatchOption {
+	return func(o *batchOptions) {
+		o.ordered = true
+	}
+}
+
+// BatchError is returned by a batch wrap when records are processed concurrently and one or more records fail. It
+// contains the error of every record that failed, errors.Is and errors.As will match against any of them.
+type BatchError struct {
+	// Errors contains the error of each record that failed, in the order the records were received.
+	Errors []RecordError
+}
+
+// RecordError is the error of a single record within a BatchError.
+type RecordError struct {
+	// Index is the position of the record within the batch.
+	Index int
+	// Err is the error returned while processing the record.
+	Err error
+}
+
+func (e BatchError) Error() string {
+	var s []string
+
+	for _, r := range e.Errors {
+		s = append(s, fmt.Sprintf("record %d: %s", r.Index, r.Err))
+	}
+
+	return fmt.Sprintf("%d records failed: %s", len(e.Errors), strings.Join(s, "; "))
+}
+
+func (e BatchError) Is(target error) bool {
+	for _, r := range e.Errors {
+		if errors.Is(r.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e BatchError) As(target any) bool {
+	for _, r := range e.Errors {
+		if errors.As(r.Err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newBatchOptions(opts []BatchOption) batchOptions {
+	o := batchOptions{concurrency: 1}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+
+	return o
+}
+
+// batchRun calls fn for count records, returning the output and error of each record by index. Without concurrency
+// records are processed in the order received. With concurrency records are grouped into lanes, each lane is processed
+// in order by a single worker, with up to concurrency lanes in progress at once. If ordering is enabled lanes are formed
+// of records sharing the same key, and a failure skips the rest of the lane, otherwise every record is its own lane.
+// If failFast is set, the first error cancels the shared context and all records not yet started are skipped.
+// Cancellation of the parent context does not skip records, next is responsible for handling it.
+func batchRun(ctx context.Context, o batchOptions, failFast bool, count int, key func(int) string, fn func(context.Context, int) ([]byte, error)) ([][]byte, []error) {
+	data := make([][]byte, count)
+	errs := make([]error, count)
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if o.concurrency == 1 {
+		failedKeys := map[string]bool{}
+
+		for i := 0; i < count; i++ {
+			if (ctx.Err() != nil && parent.Err() == nil) || (o.ordered && failedKeys[key(i)]) {
+				errs[i] = ErrBatchSkipped
+				continue
+			}
+
+			if d, err := fn(ctx, i); err != nil {
+				errs[i] = err
+
+				if o.ordered {
+					failedKeys[key(i)] = true
+				}
+
+				if failFast {
+					cancel()
+				}
+			} else {
+				data[i] = d
+			}
+		}
+
+		return data, errs
+	}
+
+	var lanes [][]int
+
+	if o.ordered {
+		laneByKey := map[string]int{}
+
+		for i := 0; i < count; i++ {
+			k := key(i)
+
+			if l, ok := laneByKey[k]; ok {
+				lanes[l] = append(lanes[l], i)
+			} else {
+				laneByKey[k] = len(lanes)
+				lanes = append(lanes, []int{i})
+			}
+		}
+	} else {
+		for i := 0; i < count; i++ {
+			lanes = append(lanes, []int{i})
+		}
+	}
+
+	work := make(chan []int)
+	wg := &sync.WaitGroup{}
+
+	workers := o.concurrency
+	if workers > len(lanes) {
+		workers = len(lanes)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for lane := range work {
+				failed := false
+
+				for _, i := range lane {
+					if failed || (ctx.Err() != nil && parent.Err() == nil) {
+						errs[i] = ErrBatchSkipped
+						continue
+					}
+
+					if d, err := fn(ctx, i); err != nil {
+						errs[i] = err
+						failed = o.ordered
+
+						if failFast {
+							cancel()
+						}
+					} else {
+						data[i] = d
+					}
+				}
+			}
+		}()
+	}
+
+	for _, lane := range lanes {
+		work <- lane
+	}
+
+	close(work)
+	wg.Wait()
+
+	return data, errs
+}
+
+// batchConcat concatenates the output of each record, returning an error if any record failed. Skipped records are
+// not treated as errors. If records were processed one at a time the first error is returned directly, otherwise a
+// BatchError containing every error is returned.
+func batchConcat(o batchOptions, data [][]byte, errs []error) ([]byte, error) {
+	var ret []byte
+	var be BatchError
+
+	for i, err := range errs {
+		if err != nil && err != ErrBatchSkipped {
+			be.Errors = append(be.Errors, RecordError{Index: i, Err: err})
+		}
+	}
+
+	if len(be.Errors) > 0 {
+		if o.concurrency == 1 {
+			return nil, be.Errors[0].Err
+		}
+
+		return nil, be
+	}
+
+	for _, d := range data {
+		ret = append(ret, d...)
+	}
+
+	return ret, nil
+}
+
+// batchCheckpoint returns the index of the first record that failed or was skipped, or -1 if all records succeeded.
+func batchCheckpoint(errs []error) int {
+	for i, err := range errs {
+		if err != nil {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,153 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithConcurrency(t *testing.T) {
+	sqsEvent := func(count int) events.SQSEvent {
+		var e events.SQSEvent
+
+		for i := 0; i < count; i++ {
+			e.Records = append(e.Records, events.SQSMessage{MessageId: strconv.Itoa(i), Body: strconv.Itoa(i)})
+		}
+
+		return e
+	}
+
+	t.Run("records are processed concurrently up to the limit, and the output is in the order received", func(t *testing.T) {
+		var inFlight, maxInFlight int32
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			now := atomic.AddInt32(&inFlight, 1)
+			defer atomic.AddInt32(&inFlight, -1)
+
+			for {
+				max := atomic.LoadInt32(&maxInFlight)
+				if now <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, now) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			return []byte(d), nil
+		}
+
+		d, err := SQS(next, WithConcurrency(3))(context.TODO(), sqsEvent(10))
+		assert.NoError(t, err)
+		assert.Equal(t, "0123456789", string(d))
+		assert.Equal(t, int32(3), maxInFlight)
+	})
+
+	t.Run("an error cancels the shared context and every error is returned in a BatchError", func(t *testing.T) {
+		started := make(chan struct{})
+
+		next := func(ctx context.Context, d string) ([]byte, error) {
+			switch d {
+			case "0":
+				<-started
+				return nil, io.ErrUnexpectedEOF
+			case "1":
+				close(started)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			default:
+				return nil, nil
+			}
+		}
+
+		d, err := SQS(next, WithConcurrency(2))(context.TODO(), sqsEvent(5))
+		assert.Nil(t, d)
+
+		var be BatchError
+		assert.True(t, errors.As(err, &be))
+		assert.Len(t, be.Errors, 2)
+		assert.Equal(t, 0, be.Errors[0].Index)
+		assert.Equal(t, 1, be.Errors[1].Index)
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("batch item failures are reported for every failed record without cancelling others", func(t *testing.T) {
+		next := func(_ context.Context, d string) ([]byte, error) {
+			if d == "1" || d == "3" {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, nil
+		}
+
+		resp, err := SQSBatchItemFailures(next, WithConcurrency(4))(context.TODO(), sqsEvent(5))
+		assert.NoError(t, err)
+		assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "1"}, {ItemIdentifier: "3"}}, resp.BatchItemFailures)
+	})
+}
+
+func TestWithOrdered(t *testing.T) {
+	keys := func(id string) map[string]events.DynamoDBAttributeValue {
+		return map[string]events.DynamoDBAttributeValue{"id": events.NewStringAttribute(id)}
+	}
+
+	in := events.DynamoDBEvent{
+		Records: []events.DynamoDBEventRecord{
+			{EventID: "a1", Change: events.DynamoDBStreamRecord{Keys: keys("a"), SequenceNumber: "1"}},
+			{EventID: "b1", Change: events.DynamoDBStreamRecord{Keys: keys("b"), SequenceNumber: "2"}},
+			{EventID: "a2", Change: events.DynamoDBStreamRecord{Keys: keys("a"), SequenceNumber: "3"}},
+			{EventID: "b2", Change: events.DynamoDBStreamRecord{Keys: keys("b"), SequenceNumber: "4"}},
+			{EventID: "a3", Change: events.DynamoDBStreamRecord{Keys: keys("a"), SequenceNumber: "5"}},
+		},
+	}
+
+	t.Run("records sharing an ordering key are processed in order, and the output is in the order received", func(t *testing.T) {
+		lock := &sync.Mutex{}
+		seen := map[string][]string{}
+
+		next := func(_ context.Context, r events.DynamoDBEventRecord) ([]byte, error) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			k := r.Change.Keys["id"].String()
+			seen[k] = append(seen[k], r.EventID)
+			return []byte(r.EventID), nil
+		}
+
+		d, err := DynamoDBStream(next, WithConcurrency(2), WithOrdered())(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "a1b1a2b2a3", string(d))
+		assert.Equal(t, []string{"a1", "a2", "a3"}, seen["a"])
+		assert.Equal(t, []string{"b1", "b2"}, seen["b"])
+	})
+
+	t.Run("a failure skips later records with the same ordering key, and the earliest is the checkpoint", func(t *testing.T) {
+		lock := &sync.Mutex{}
+		var seen []string
+
+		next := func(_ context.Context, r events.DynamoDBEventRecord) ([]byte, error) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			seen = append(seen, r.EventID)
+
+			if r.EventID == "a2" {
+				return nil, fmt.Errorf("failed: %w", io.ErrUnexpectedEOF)
+			}
+
+			return nil, nil
+		}
+
+		resp, err := DynamoDBStreamBatchItemFailures(next, WithOrdered())(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.NotContains(t, seen, "a3")
+		assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "3"}}, resp.BatchItemFailures)
+	})
+}
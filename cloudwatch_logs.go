@@ -0,0 +1,107 @@
+package lambdawrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const cloudWatchLogsControlMessage = "CONTROL_MESSAGE"
+
+// CloudWatchLogs provides a wrapper to decode the gzip compressed, base64 encoded payload of an
+// events.CloudwatchLogsEvent, iterating through each log event it contains. Default behaviour is to concatenate the
+// []byte output from each log event, returning to the caller. Control messages, sent by CloudWatch Logs to check the
+// destination is reachable, are discarded without calling next.
+//
+// The message of each log event is provided to next as a []byte, string or unmarshalled with JSON in the same manner as
+// SQS. It is recommended you use DomainObject instead, this permits structured log lines to be decoded directly.
+//
+// The log group, log stream, owner and subscription filters are added to the context, and can be extracted with
+// CloudWatchLogsGroupFromContext, CloudWatchLogsStreamFromContext, CloudWatchLogsOwnerFromContext and
+// CloudWatchLogsSubscriptionFiltersFromContext. The full events.CloudwatchLogsLogEvent, including ID and timestamp, can
+// be extracted with CloudWatchLogsEventFromContext.
+func CloudWatchLogs[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, events.CloudwatchLogsEvent) ([]byte, error) {
+	return func(ctx context.Context, e events.CloudwatchLogsEvent) ([]byte, error) {
+		data, err := e.AWSLogs.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("CloudWatchLogs decode: %w", err)
+		}
+
+		if data.MessageType == cloudWatchLogsControlMessage {
+			return nil, nil
+		}
+
+		ctx = context.WithValue(ctx, contextKeyCloudWatchLogsGroup, data.LogGroup)
+		ctx = context.WithValue(ctx, contextKeyCloudWatchLogsStream, data.LogStream)
+		ctx = context.WithValue(ctx, contextKeyCloudWatchLogsOwner, data.Owner)
+		ctx = context.WithValue(ctx, contextKeyCloudWatchLogsSubscriptionFilters, data.SubscriptionFilters)
+
+		var ret []byte
+
+		for _, l := range data.LogEvents {
+			lCtx := context.WithValue(ctx, contextKeyCloudWatchLogsEvent, l)
+
+			if p, err := sliceStringOrUnmarshal[O]([]byte(l.Message)); err != nil {
+				return nil, fmt.Errorf("CloudWatchLogs unmarshal: %w", err)
+			} else {
+				if d, err := n(lCtx, p); err != nil {
+					return nil, fmt.Errorf("CloudWatchLogs next: %w", err)
+				} else {
+					ret = append(ret, d...)
+				}
+			}
+		}
+
+		return ret, nil
+	}
+}
+
+// CloudWatchLogsGroupFromContext retrieves a CloudWatch Logs log group from the context, for use after a
+// CloudWatchLogs wrap has been used if the application needs the log group the log event was written to.
+func CloudWatchLogsGroupFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyCloudWatchLogsGroup); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+// CloudWatchLogsStreamFromContext retrieves a CloudWatch Logs log stream from the context, for use after a
+// CloudWatchLogs wrap has been used if the application needs the log stream the log event was written to.
+func CloudWatchLogsStreamFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyCloudWatchLogsStream); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+// CloudWatchLogsOwnerFromContext retrieves the AWS account ID which owns the log group from the context, for use after
+// a CloudWatchLogs wrap has been used.
+func CloudWatchLogsOwnerFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyCloudWatchLogsOwner); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+// CloudWatchLogsSubscriptionFiltersFromContext retrieves the names of the subscription filters which matched the log
+// event from the context, for use after a CloudWatchLogs wrap has been used.
+func CloudWatchLogsSubscriptionFiltersFromContext(ctx context.Context) ([]string, bool) {
+	if val := ctx.Value(contextKeyCloudWatchLogsSubscriptionFilters); val != nil {
+		return val.([]string), true
+	} else {
+		return nil, false
+	}
+}
+
+// CloudWatchLogsEventFromContext retrieves an events.CloudwatchLogsLogEvent from the context, for use after a
+// CloudWatchLogs wrap has been used if the application needs the ID or timestamp of the log event.
+func CloudWatchLogsEventFromContext(ctx context.Context) (events.CloudwatchLogsLogEvent, bool) {
+	if val := ctx.Value(contextKeyCloudWatchLogsEvent); val != nil {
+		return val.(events.CloudwatchLogsLogEvent), true
+	} else {
+		return events.CloudwatchLogsLogEvent{}, false
+	}
+}
@@ -0,0 +1,118 @@
+package lambdawrap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestCloudWatchLogs(t *testing.T) {
+	encode := func(t *testing.T, data events.CloudwatchLogsData) events.CloudwatchLogsEvent {
+		j, err := json.Marshal(data)
+		assert.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		_, err = zw.Write(j)
+		assert.NoError(t, err)
+		assert.NoError(t, zw.Close())
+
+		return events.CloudwatchLogsEvent{AWSLogs: events.CloudwatchLogsRawData{Data: base64.StdEncoding.EncodeToString(buf.Bytes())}}
+	}
+
+	t.Run("each log event is processed, calls the next function that takes a structure, the result is aggregated and log details are available on context", func(t *testing.T) {
+		type myStruct struct {
+			Val string
+		}
+
+		in := encode(t, events.CloudwatchLogsData{
+			Owner:               "123456789012",
+			LogGroup:            "group",
+			LogStream:           "stream",
+			SubscriptionFilters: []string{"filter"},
+			MessageType:         "DATA_MESSAGE",
+			LogEvents: []events.CloudwatchLogsLogEvent{
+				{ID: "1", Message: "{\"val\": \"1\"}"},
+				{ID: "2", Message: "{\"val\": \"2\"}"},
+			},
+		})
+
+		next := func(ctx context.Context, d myStruct) ([]byte, error) {
+			group, ok := CloudWatchLogsGroupFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "group", group)
+
+			stream, ok := CloudWatchLogsStreamFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "stream", stream)
+
+			owner, ok := CloudWatchLogsOwnerFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "123456789012", owner)
+
+			filters, ok := CloudWatchLogsSubscriptionFiltersFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, []string{"filter"}, filters)
+
+			logEvent, ok := CloudWatchLogsEventFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, d.Val, logEvent.ID)
+
+			return []byte(d.Val), nil
+		}
+
+		d, err := CloudWatchLogs(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "12", string(d))
+	})
+
+	t.Run("control messages are discarded without calling next", func(t *testing.T) {
+		in := encode(t, events.CloudwatchLogsData{
+			MessageType: "CONTROL_MESSAGE",
+			LogEvents: []events.CloudwatchLogsLogEvent{
+				{ID: "1", Message: "CWL CONTROL MESSAGE: Checking health of destination Firehose."},
+			},
+		})
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			t.Fatal("next called unexpectedly")
+			return nil, nil
+		}
+
+		d, err := CloudWatchLogs(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("a payload which can not be decoded will result in an error", func(t *testing.T) {
+		in := events.CloudwatchLogsEvent{AWSLogs: events.CloudwatchLogsRawData{Data: "not base64!"}}
+
+		d, err := CloudWatchLogs[string](nil)(context.TODO(), in)
+		assert.Error(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("an error from next will result in an error", func(t *testing.T) {
+		in := encode(t, events.CloudwatchLogsData{
+			MessageType: "DATA_MESSAGE",
+			LogEvents: []events.CloudwatchLogsLogEvent{
+				{ID: "1", Message: "1"},
+			},
+		})
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		d, err := CloudWatchLogs(next)(context.TODO(), in)
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.Nil(t, d)
+	})
+}
@@ -13,3 +13,7 @@ func (_ jsonCodec) Marshal(v any) ([]byte, error) {
 func (_ jsonCodec) Unmarshal(data []byte, v any) error {
 	return json.Unmarshal(data, v)
 }
+
+func (_ jsonCodec) ContentType() string {
+	return "application/json"
+}
@@ -15,3 +15,7 @@ func (_ yamlCodec) Marshal(v any) ([]byte, error) {
 func (_ yamlCodec) Unmarshal(data []byte, v any) error {
 	return yaml.Unmarshal(data, v)
 }
+
+func (_ yamlCodec) ContentType() string {
+	return "application/yaml"
+}
@@ -6,4 +6,32 @@ const (
 	contextKeyS3Entity = contextKey("S3_ENTITY")
 	contextKeySNSARN   = contextKey("SNS_ARN")
 	contextKeySQSARN   = contextKey("SQS_ARN")
+
+	contextKeyS3LineNumber     = contextKey("S3_LINE_NUMBER")
+	contextKeyS3ObjectMetadata = contextKey("S3_OBJECT_METADATA")
+
+	contextKeySQSMessage = contextKey("SQS_MESSAGE")
+	contextKeySNSEntity  = contextKey("SNS_ENTITY")
+
+	contextKeyKinesisPartitionKey    = contextKey("KINESIS_PARTITION_KEY")
+	contextKeyKinesisSequenceNumber  = contextKey("KINESIS_SEQUENCE_NUMBER")
+	contextKeyKinesisStreamARN       = contextKey("KINESIS_STREAM_ARN")
+	contextKeyKinesisExplicitHashKey = contextKey("KINESIS_EXPLICIT_HASH_KEY")
+
+	contextKeyFirehoseRecordID = contextKey("FIREHOSE_RECORD_ID")
+
+	contextKeyCloudWatchLogsGroup               = contextKey("CLOUDWATCH_LOGS_GROUP")
+	contextKeyCloudWatchLogsStream              = contextKey("CLOUDWATCH_LOGS_STREAM")
+	contextKeyCloudWatchLogsOwner               = contextKey("CLOUDWATCH_LOGS_OWNER")
+	contextKeyCloudWatchLogsSubscriptionFilters = contextKey("CLOUDWATCH_LOGS_SUBSCRIPTION_FILTERS")
+	contextKeyCloudWatchLogsEvent               = contextKey("CLOUDWATCH_LOGS_EVENT")
+
+	contextKeyHTTPRequest  = contextKey("HTTP_REQUEST")
+	contextKeyHTTPResponse = contextKey("HTTP_RESPONSE")
+
+	contextKeyEventBridgeEvent = contextKey("EVENTBRIDGE_EVENT")
+
+	contextKeyDynamoDBEntity = contextKey("DYNAMODB_ENTITY")
+
+	contextKeyColdStart = contextKey("COLD_START")
 )
@@ -2,24 +2,68 @@ package lambdawrap
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
 )
 
 // DynamoDBStream provides a wrapper to iterate through multiple events.DynamoDBEvent. Default behaviour is to
 // concatenate the []byte output from each message, returning to the caller.
-func DynamoDBStream(n func(context.Context, events.DynamoDBEventRecord) ([]byte, error)) func(context.Context, events.DynamoDBEvent) ([]byte, error) {
-	return func(ctx context.Context, e events.DynamoDBEvent) ([]byte, error) {
-		var ret []byte
+//
+// Records can be processed concurrently with WithConcurrency, and WithOrdered ensures that records for the same item
+// are processed in order.
+func DynamoDBStream(n func(context.Context, events.DynamoDBEventRecord) ([]byte, error), opts ...BatchOption) func(context.Context, events.DynamoDBEvent) ([]byte, error) {
+	o := newBatchOptions(opts)
 
-		for _, r := range e.Records {
-			if d, err := n(ctx, r); err != nil {
+	return func(ctx context.Context, e events.DynamoDBEvent) ([]byte, error) {
+		data, errs := batchRun(ctx, o, true, len(e.Records), dynamoDBOrderingKey(e.Records), func(ctx context.Context, i int) ([]byte, error) {
+			if d, err := n(ctx, e.Records[i]); err != nil {
 				return nil, fmt.Errorf("DynamoDBStream next: %w", err)
 			} else {
-				ret = append(ret, d...)
+				return d, nil
 			}
+		})
+
+		return batchConcat(o, data, errs)
+	}
+}
+
+// DynamoDBStreamBatchItemFailures provides a wrapper to iterate through multiple events.DynamoDBEvent, reporting the
+// first record that fails as an events.DynamoDBEventResponse. Processing stops at the first record that returns an
+// error from next, its sequence number is returned as the only BatchItemFailures entry. Lambda treats this as a
+// checkpoint, records before it are considered committed, the failing record and all those after it will be retried.
+// The Lambda event source mapping must have ReportBatchItemFailures enabled, otherwise the response is ignored.
+//
+// The []byte output of next is discarded, use Output if the output of each record is needed.
+//
+// Records can be processed concurrently with WithConcurrency, in which case the checkpoint is the earliest record that
+// failed or was skipped. It is recommended that WithOrdered is also used, so changes to the same item are not applied
+// out of order.
+func DynamoDBStreamBatchItemFailures(n func(context.Context, events.DynamoDBEventRecord) ([]byte, error), opts ...BatchOption) func(context.Context, events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	o := newBatchOptions(opts)
+
+	return func(ctx context.Context, e events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+		ret := events.DynamoDBEventResponse{BatchItemFailures: []events.DynamoDBBatchItemFailure{}}
+
+		_, errs := batchRun(ctx, o, true, len(e.Records), dynamoDBOrderingKey(e.Records), func(ctx context.Context, i int) ([]byte, error) {
+			return n(ctx, e.Records[i])
+		})
+
+		if i := batchCheckpoint(errs); i >= 0 {
+			ret.BatchItemFailures = append(ret.BatchItemFailures, events.DynamoDBBatchItemFailure{ItemIdentifier: e.Records[i].Change.SequenceNumber})
 		}
 
 		return ret, nil
 	}
 }
+
+func dynamoDBOrderingKey(r []events.DynamoDBEventRecord) func(int) string {
+	return func(i int) string {
+		// encoding/json sorts map keys, so the key attributes marshal consistently.
+		if k, err := json.Marshal(r[i].Change.Keys); err == nil {
+			return r[i].EventSourceArn + string(k)
+		}
+
+		return r[i].EventSourceArn
+	}
+}
@@ -0,0 +1,178 @@
+package lambdawrap
+
+import (
+	"bytes"
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"math/big"
+	"sort"
+)
+
+const (
+	// DynamoDBEventInsert is the EventName of a stream record for a new item.
+	DynamoDBEventInsert = "INSERT"
+	// DynamoDBEventModify is the EventName of a stream record for an updated item.
+	DynamoDBEventModify = "MODIFY"
+	// DynamoDBEventRemove is the EventName of a stream record for a deleted item.
+	DynamoDBEventRemove = "REMOVE"
+)
+
+// DynamoDBEvents provides a function to route an events.DynamoDBEventRecord to a handler based upon its EventName, it
+// is chained from DynamoDBStream or DynamoDBStreamBatchItemFailures. A nil handler ignores records of that type, an
+// unknown EventName results in an error.
+//
+// Example:
+//
+//   DynamoDBStream(DynamoDBEvents(DynamoDBImage(onInsert), DynamoDBImage(onModify), nil))
+func DynamoDBEvents(insert, modify, remove func(context.Context, events.DynamoDBEventRecord) ([]byte, error)) func(context.Context, events.DynamoDBEventRecord) ([]byte, error) {
+	m := map[string]func(context.Context, events.DynamoDBEventRecord) ([]byte, error){
+		DynamoDBEventInsert: insert,
+		DynamoDBEventModify: modify,
+		DynamoDBEventRemove: remove,
+	}
+
+	for k, v := range m {
+		if v == nil {
+			m[k] = Nop[events.DynamoDBEventRecord]()
+		}
+	}
+
+	return Switch(func(e events.DynamoDBEventRecord) string {
+		return e.EventName
+	}, m)
+}
+
+// DynamoDBChangedAttributes returns the sorted names of the top level attributes which differ between the OldImage and
+// NewImage of a stream record, including attributes which were added or removed. Sets are compared without regard to
+// order. The stream must use the NEW_AND_OLD_IMAGES view type, otherwise every attribute of the image present is
+// reported.
+func DynamoDBChangedAttributes(e events.DynamoDBEventRecord) []string {
+	var ret []string
+
+	for name, o := range e.Change.OldImage {
+		if n, ok := e.Change.NewImage[name]; !ok || !dynamoDBAttributeEqual(o, n) {
+			ret = append(ret, name)
+		}
+	}
+
+	for name := range e.Change.NewImage {
+		if _, ok := e.Change.OldImage[name]; !ok {
+			ret = append(ret, name)
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// DynamoDBAttributeChanged provides a function for use with Filter or Match, which matches if any of the named top
+// level attributes differ between the OldImage and NewImage of the stream record, see DynamoDBChangedAttributes.
+//
+// Example:
+//
+//   DynamoDBStream(Filter(DynamoDBAttributeChanged("status"), DynamoDBImage(onStatusChange)))
+func DynamoDBAttributeChanged(names ...string) func(context.Context, events.DynamoDBEventRecord) (bool, error) {
+	return func(_ context.Context, e events.DynamoDBEventRecord) (bool, error) {
+		changed := DynamoDBChangedAttributes(e)
+
+		for _, name := range names {
+			if i := sort.SearchStrings(changed, name); i < len(changed) && changed[i] == name {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+}
+
+// dynamoDBAttributeEqual compares two attribute values, the members of sets are compared without regard to order.
+func dynamoDBAttributeEqual(a, b events.DynamoDBAttributeValue) bool {
+	if a.DataType() != b.DataType() {
+		return false
+	}
+
+	switch a.DataType() {
+	case events.DataTypeNull:
+		return true
+	case events.DataTypeString:
+		return a.String() == b.String()
+	case events.DataTypeNumber:
+		return dynamoDBNumberEqual(a.Number(), b.Number())
+	case events.DataTypeBinary:
+		return bytes.Equal(a.Binary(), b.Binary())
+	case events.DataTypeBoolean:
+		return a.Boolean() == b.Boolean()
+	case events.DataTypeList:
+		al, bl := a.List(), b.List()
+		if len(al) != len(bl) {
+			return false
+		}
+
+		for i := range al {
+			if !dynamoDBAttributeEqual(al[i], bl[i]) {
+				return false
+			}
+		}
+
+		return true
+	case events.DataTypeMap:
+		am, bm := a.Map(), b.Map()
+		if len(am) != len(bm) {
+			return false
+		}
+
+		for k, av := range am {
+			if bv, ok := bm[k]; !ok || !dynamoDBAttributeEqual(av, bv) {
+				return false
+			}
+		}
+
+		return true
+	case events.DataTypeStringSet:
+		return dynamoDBSetEqual(a.StringSet(), b.StringSet(), func(s string) string { return s })
+	case events.DataTypeNumberSet:
+		return dynamoDBSetEqual(a.NumberSet(), b.NumberSet(), dynamoDBNumberCanonical)
+	case events.DataTypeBinarySet:
+		return dynamoDBSetEqual(a.BinarySet(), b.BinarySet(), func(b []byte) string { return string(b) })
+	}
+
+	return false
+}
+
+// dynamoDBSetEqual compares the members of two sets without regard to order, using key to identify each member.
+func dynamoDBSetEqual[T any](a, b []T, key func(T) string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	members := make(map[string]int, len(a))
+
+	for _, m := range a {
+		members[key(m)]++
+	}
+
+	for _, m := range b {
+		k := key(m)
+		if members[k] == 0 {
+			return false
+		}
+		members[k]--
+	}
+
+	return true
+}
+
+// dynamoDBNumberEqual compares two DynamoDB numbers, which may be formatted differently, e.g. 1 and 1.0.
+func dynamoDBNumberEqual(a, b string) bool {
+	return dynamoDBNumberCanonical(a) == dynamoDBNumberCanonical(b)
+}
+
+// dynamoDBNumberCanonical returns a canonical representation of a DynamoDB number, falling back to the number as
+// provided if it can not be parsed.
+func dynamoDBNumberCanonical(n string) string {
+	if r, ok := new(big.Rat).SetString(n); ok {
+		return r.RatString()
+	}
+
+	return n
+}
@@ -0,0 +1,127 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDynamoDBEvents(t *testing.T) {
+	handler := func(name string) func(context.Context, events.DynamoDBEventRecord) ([]byte, error) {
+		return func(_ context.Context, _ events.DynamoDBEventRecord) ([]byte, error) {
+			return []byte(name), nil
+		}
+	}
+
+	t.Run("records are routed by event name", func(t *testing.T) {
+		f := DynamoDBEvents(handler("i"), handler("m"), handler("r"))
+
+		for name, expected := range map[string]string{"INSERT": "i", "MODIFY": "m", "REMOVE": "r"} {
+			d, err := f(context.TODO(), events.DynamoDBEventRecord{EventName: name})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, string(d))
+		}
+	})
+
+	t.Run("nil handlers ignore records", func(t *testing.T) {
+		d, err := DynamoDBEvents(handler("i"), nil, nil)(context.TODO(), events.DynamoDBEventRecord{EventName: "REMOVE"})
+		assert.NoError(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("unknown event names return an error", func(t *testing.T) {
+		_, err := DynamoDBEvents(nil, nil, nil)(context.TODO(), events.DynamoDBEventRecord{EventName: "UNKNOWN"})
+		assert.Error(t, err)
+	})
+}
+
+func TestDynamoDBChangedAttributes(t *testing.T) {
+	record := func(old, new map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{EventName: "MODIFY", Change: events.DynamoDBStreamRecord{OldImage: old, NewImage: new}}
+	}
+
+	t.Run("reports changed, added and removed attributes", func(t *testing.T) {
+		e := record(
+			map[string]events.DynamoDBAttributeValue{
+				"pk":      events.NewStringAttribute("1"),
+				"status":  events.NewStringAttribute("open"),
+				"removed": events.NewStringAttribute("x"),
+			},
+			map[string]events.DynamoDBAttributeValue{
+				"pk":     events.NewStringAttribute("1"),
+				"status": events.NewStringAttribute("closed"),
+				"added":  events.NewStringAttribute("y"),
+			},
+		)
+
+		assert.Equal(t, []string{"added", "removed", "status"}, DynamoDBChangedAttributes(e))
+	})
+
+	t.Run("equivalent values are not reported", func(t *testing.T) {
+		image := func(tags []string, n string) map[string]events.DynamoDBAttributeValue {
+			return map[string]events.DynamoDBAttributeValue{
+				"tags":   events.NewStringSetAttribute(tags),
+				"nums":   events.NewNumberSetAttribute([]string{n, "2"}),
+				"bin":    events.NewBinarySetAttribute([][]byte{{1}, {2}}),
+				"count":  events.NewNumberAttribute(n),
+				"null":   events.NewNullAttribute(),
+				"bool":   events.NewBooleanAttribute(true),
+				"data":   events.NewBinaryAttribute([]byte{1}),
+				"nested": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"l": events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewStringAttribute("a")})}),
+			}
+		}
+
+		e := record(image([]string{"a", "b"}, "1"), image([]string{"b", "a"}, "1.0"))
+		assert.Empty(t, DynamoDBChangedAttributes(e))
+	})
+
+	t.Run("changes within nested values and types are reported", func(t *testing.T) {
+		e := record(
+			map[string]events.DynamoDBAttributeValue{
+				"nested": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"a": events.NewStringAttribute("1")}),
+				"list":   events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewStringAttribute("a")}),
+				"type":   events.NewStringAttribute("1"),
+				"set":    events.NewStringSetAttribute([]string{"a", "a"}),
+			},
+			map[string]events.DynamoDBAttributeValue{
+				"nested": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"a": events.NewStringAttribute("2")}),
+				"list":   events.NewListAttribute([]events.DynamoDBAttributeValue{events.NewStringAttribute("b")}),
+				"type":   events.NewNumberAttribute("1"),
+				"set":    events.NewStringSetAttribute([]string{"a", "b"}),
+			},
+		)
+
+		assert.Equal(t, []string{"list", "nested", "set", "type"}, DynamoDBChangedAttributes(e))
+	})
+}
+
+func TestDynamoDBAttributeChanged(t *testing.T) {
+	e := events.DynamoDBEventRecord{
+		EventName: "MODIFY",
+		Change: events.DynamoDBStreamRecord{
+			OldImage: map[string]events.DynamoDBAttributeValue{"status": events.NewStringAttribute("open"), "name": events.NewStringAttribute("a")},
+			NewImage: map[string]events.DynamoDBAttributeValue{"status": events.NewStringAttribute("closed"), "name": events.NewStringAttribute("a")},
+		},
+	}
+
+	t.Run("matches if any named attribute changed", func(t *testing.T) {
+		ok, err := DynamoDBAttributeChanged("other", "status")(context.TODO(), e)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("does not match if the named attributes are unchanged", func(t *testing.T) {
+		ok, err := DynamoDBAttributeChanged("name")(context.TODO(), e)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("can be used with Filter", func(t *testing.T) {
+		d, err := Filter(DynamoDBAttributeChanged("name"), func(_ context.Context, _ events.DynamoDBEventRecord) ([]byte, error) {
+			return []byte("called"), nil
+		})(context.TODO(), e)
+		assert.NoError(t, err)
+		assert.Nil(t, d)
+	})
+}
@@ -0,0 +1,86 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"strings"
+)
+
+// ErrDynamoDBUnknownEntity is returned by DynamoDBEntities if no handler matches a record and no fallback is provided.
+var ErrDynamoDBUnknownEntity = errors.New("unknown dynamodb entity")
+
+// DynamoDBDiscriminator identifies the entity type of a stream record, for use with DynamoDBEntities. An empty string
+// is returned if the entity type can not be determined.
+type DynamoDBDiscriminator func(events.DynamoDBEventRecord) string
+
+// DynamoDBKeyPrefix provides a DynamoDBDiscriminator which uses the prefix of a string key attribute, up to the first
+// separator, e.g. ORDER for a partition key of ORDER#123 with a separator of #. If the separator is not present the
+// whole key is used.
+func DynamoDBKeyPrefix(attribute string, separator string) DynamoDBDiscriminator {
+	return func(e events.DynamoDBEventRecord) string {
+		v := dynamoDBStringAttribute(e.Change.Keys, attribute)
+		prefix, _, _ := strings.Cut(v, separator)
+		return prefix
+	}
+}
+
+// DynamoDBTypeAttribute provides a DynamoDBDiscriminator which uses the value of a string attribute of the item, such
+// as type. The NewImage is used if present, otherwise the OldImage, so the stream must include item images.
+func DynamoDBTypeAttribute(attribute string) DynamoDBDiscriminator {
+	return func(e events.DynamoDBEventRecord) string {
+		if v := dynamoDBStringAttribute(e.Change.NewImage, attribute); v != "" {
+			return v
+		}
+
+		return dynamoDBStringAttribute(e.Change.OldImage, attribute)
+	}
+}
+
+// DynamoDBEntities allows a single stream from a table using single table design to be handled by a function per entity
+// type, selecting the function using the entity type provided by d. Using DynamoDBImage for each handler permits each
+// entity to be decoded into its own type. If no handler matches, fallback is called, if fallback is nil an error
+// wrapping ErrDynamoDBUnknownEntity is returned. Nop can be used as the fallback to ignore unknown entities.
+//
+// The entity type is added to the context, and can be extracted with DynamoDBEntityFromContext.
+//
+// Example:
+//
+//   DynamoDBStream(DynamoDBEntities(DynamoDBKeyPrefix("pk", "#"), map[string]func(context.Context, events.DynamoDBEventRecord) ([]byte, error){
+//     "ORDER":    DynamoDBImage(onOrder),
+//     "CUSTOMER": DynamoDBImage(onCustomer),
+//   }, Nop[events.DynamoDBEventRecord]()))
+func DynamoDBEntities(d DynamoDBDiscriminator, m map[string]func(context.Context, events.DynamoDBEventRecord) ([]byte, error), fallback func(context.Context, events.DynamoDBEventRecord) ([]byte, error)) func(context.Context, events.DynamoDBEventRecord) ([]byte, error) {
+	return func(ctx context.Context, e events.DynamoDBEventRecord) ([]byte, error) {
+		entity := d(e)
+		ctx = context.WithValue(ctx, contextKeyDynamoDBEntity, entity)
+
+		if fn, ok := m[entity]; ok {
+			return fn(ctx, e)
+		} else if fallback != nil {
+			return fallback(ctx, e)
+		} else {
+			return nil, fmt.Errorf("%w: %q", ErrDynamoDBUnknownEntity, entity)
+		}
+	}
+}
+
+// DynamoDBEntityFromContext retrieves the entity type of the record from the context, for use after a DynamoDBEntities
+// wrap has been used.
+func DynamoDBEntityFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyDynamoDBEntity); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+// dynamoDBStringAttribute returns the value of a string attribute, or an empty string if it is absent or not a string.
+func dynamoDBStringAttribute(m map[string]events.DynamoDBAttributeValue, name string) string {
+	if v, ok := m[name]; ok && v.DataType() == events.DataTypeString {
+		return v.String()
+	}
+
+	return ""
+}
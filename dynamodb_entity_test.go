@@ -0,0 +1,113 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDynamoDBKeyPrefix(t *testing.T) {
+	record := func(pk events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{Change: events.DynamoDBStreamRecord{Keys: map[string]events.DynamoDBAttributeValue{"pk": pk}}}
+	}
+
+	t.Run("provides the prefix of the key before the separator", func(t *testing.T) {
+		assert.Equal(t, "ORDER", DynamoDBKeyPrefix("pk", "#")(record(events.NewStringAttribute("ORDER#123#x"))))
+	})
+
+	t.Run("provides the whole key if the separator is absent", func(t *testing.T) {
+		assert.Equal(t, "ORDER", DynamoDBKeyPrefix("pk", "#")(record(events.NewStringAttribute("ORDER"))))
+	})
+
+	t.Run("provides an empty string if the key is absent or not a string", func(t *testing.T) {
+		assert.Equal(t, "", DynamoDBKeyPrefix("sk", "#")(record(events.NewStringAttribute("ORDER#1"))))
+		assert.Equal(t, "", DynamoDBKeyPrefix("pk", "#")(record(events.NewNumberAttribute("1"))))
+	})
+}
+
+func TestDynamoDBTypeAttribute(t *testing.T) {
+	t.Run("prefers the new image", func(t *testing.T) {
+		e := events.DynamoDBEventRecord{Change: events.DynamoDBStreamRecord{
+			OldImage: map[string]events.DynamoDBAttributeValue{"type": events.NewStringAttribute("old")},
+			NewImage: map[string]events.DynamoDBAttributeValue{"type": events.NewStringAttribute("new")},
+		}}
+
+		assert.Equal(t, "new", DynamoDBTypeAttribute("type")(e))
+	})
+
+	t.Run("falls back to the old image", func(t *testing.T) {
+		e := events.DynamoDBEventRecord{Change: events.DynamoDBStreamRecord{
+			OldImage: map[string]events.DynamoDBAttributeValue{"type": events.NewStringAttribute("old")},
+		}}
+
+		assert.Equal(t, "old", DynamoDBTypeAttribute("type")(e))
+	})
+}
+
+func TestDynamoDBEntities(t *testing.T) {
+	type order struct {
+		PK    string  `dynamodbav:"pk"`
+		Total float64 `dynamodbav:"total"`
+	}
+
+	type customer struct {
+		PK   string `dynamodbav:"pk"`
+		Name string `dynamodbav:"name"`
+	}
+
+	record := func(image map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{
+			EventName: "INSERT",
+			Change:    events.DynamoDBStreamRecord{Keys: map[string]events.DynamoDBAttributeValue{"pk": image["pk"]}, NewImage: image},
+		}
+	}
+
+	var orders []order
+	var customers []customer
+
+	m := map[string]func(context.Context, events.DynamoDBEventRecord) ([]byte, error){
+		"ORDER": DynamoDBImage(func(_ context.Context, c DynamoDBChange[order]) ([]byte, error) {
+			orders = append(orders, *c.New)
+			return nil, nil
+		}),
+		"CUSTOMER": DynamoDBImage(func(ctx context.Context, c DynamoDBChange[customer]) ([]byte, error) {
+			entity, _ := DynamoDBEntityFromContext(ctx)
+			assert.Equal(t, "CUSTOMER", entity)
+			customers = append(customers, *c.New)
+			return nil, nil
+		}),
+	}
+
+	t.Run("records are dispatched to a typed handler per entity", func(t *testing.T) {
+		in := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+			record(map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("ORDER#1"), "total": events.NewNumberAttribute("9.5")}),
+			record(map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("CUSTOMER#1"), "name": events.NewStringAttribute("Bob")}),
+		}}
+
+		_, err := DynamoDBStream(DynamoDBEntities(DynamoDBKeyPrefix("pk", "#"), m, nil))(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []order{{PK: "ORDER#1", Total: 9.5}}, orders)
+		assert.Equal(t, []customer{{PK: "CUSTOMER#1", Name: "Bob"}}, customers)
+	})
+
+	t.Run("unknown entities are passed to the fallback", func(t *testing.T) {
+		var entity string
+
+		fallback := func(ctx context.Context, _ events.DynamoDBEventRecord) ([]byte, error) {
+			entity, _ = DynamoDBEntityFromContext(ctx)
+			return []byte("fallback"), nil
+		}
+
+		d, err := DynamoDBEntities(DynamoDBKeyPrefix("pk", "#"), m, fallback)(context.TODO(), record(map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("INVOICE#1")}))
+		assert.NoError(t, err)
+		assert.Equal(t, "fallback", string(d))
+		assert.Equal(t, "INVOICE", entity)
+	})
+
+	t.Run("unknown entities without a fallback return an error", func(t *testing.T) {
+		_, err := DynamoDBEntities(DynamoDBKeyPrefix("pk", "#"), m, nil)(context.TODO(), record(map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("INVOICE#1")}))
+		assert.True(t, errors.Is(err, ErrDynamoDBUnknownEntity))
+	})
+}
@@ -0,0 +1,62 @@
+package lambdawrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// DynamoDBChange is a change to an item from a DynamoDB stream, with the item images decoded into T.
+type DynamoDBChange[T any] struct {
+	// EventName is the type of change, INSERT, MODIFY or REMOVE.
+	EventName string
+	// Keys contains the primary key attributes of the item.
+	Keys map[string]events.DynamoDBAttributeValue
+	// Old is the item before the change, it is nil if the stream does not include old images, or for an INSERT.
+	Old *T
+	// New is the item after the change, it is nil if the stream does not include new images, or for a REMOVE.
+	New *T
+}
+
+// DynamoDBImage consumes an events.DynamoDBEventRecord, decoding the OldImage and NewImage into T with
+// DynamoDBUnmarshal and providing a DynamoDBChange to the next function. It is chained from DynamoDBStream or
+// DynamoDBStreamBatchItemFailures.
+//
+// Example:
+//
+//   type order struct {
+//     ID    string  `dynamodbav:"pk"`
+//     Total float64 `dynamodbav:"total"`
+//   }
+//
+//   DynamoDBStream(DynamoDBImage(func(ctx context.Context, c DynamoDBChange[order]) ([]byte, error) { ... }))
+func DynamoDBImage[T any](n func(context.Context, DynamoDBChange[T]) ([]byte, error)) func(context.Context, events.DynamoDBEventRecord) ([]byte, error) {
+	return func(ctx context.Context, e events.DynamoDBEventRecord) ([]byte, error) {
+		c := DynamoDBChange[T]{
+			EventName: e.EventName,
+			Keys:      e.Change.Keys,
+		}
+
+		if len(e.Change.OldImage) > 0 {
+			c.Old = new(T)
+
+			if err := DynamoDBUnmarshal(e.Change.OldImage, c.Old); err != nil {
+				return nil, fmt.Errorf("DynamoDBImage old image unmarshal: %w", err)
+			}
+		}
+
+		if len(e.Change.NewImage) > 0 {
+			c.New = new(T)
+
+			if err := DynamoDBUnmarshal(e.Change.NewImage, c.New); err != nil {
+				return nil, fmt.Errorf("DynamoDBImage new image unmarshal: %w", err)
+			}
+		}
+
+		if d, err := n(ctx, c); err != nil {
+			return nil, fmt.Errorf("DynamoDBImage next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDynamoDBImage(t *testing.T) {
+	type item struct {
+		ID    string `dynamodbav:"pk"`
+		Count int    `dynamodbav:"count"`
+	}
+
+	record := func(name string, old, new map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+		return events.DynamoDBEventRecord{
+			EventName: name,
+			Change: events.DynamoDBStreamRecord{
+				Keys:     map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("1")},
+				OldImage: old,
+				NewImage: new,
+			},
+		}
+	}
+
+	t.Run("old and new images are decoded into the change", func(t *testing.T) {
+		var change DynamoDBChange[item]
+
+		next := func(_ context.Context, c DynamoDBChange[item]) ([]byte, error) {
+			change = c
+			return []byte("ok"), nil
+		}
+
+		in := record("MODIFY",
+			map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("1"), "count": events.NewNumberAttribute("1")},
+			map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("1"), "count": events.NewNumberAttribute("2")},
+		)
+
+		d, err := DynamoDBImage(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", string(d))
+
+		assert.Equal(t, "MODIFY", change.EventName)
+		assert.Equal(t, "1", change.Keys["pk"].String())
+		assert.Equal(t, &item{ID: "1", Count: 1}, change.Old)
+		assert.Equal(t, &item{ID: "1", Count: 2}, change.New)
+	})
+
+	t.Run("missing images are nil", func(t *testing.T) {
+		var change DynamoDBChange[item]
+
+		next := func(_ context.Context, c DynamoDBChange[item]) ([]byte, error) {
+			change = c
+			return nil, nil
+		}
+
+		_, err := DynamoDBImage(next)(context.TODO(), record("INSERT", nil, map[string]events.DynamoDBAttributeValue{"pk": events.NewStringAttribute("1")}))
+		assert.NoError(t, err)
+		assert.Nil(t, change.Old)
+		assert.Equal(t, &item{ID: "1"}, change.New)
+	})
+
+	t.Run("decode errors are returned without calling next", func(t *testing.T) {
+		called := false
+
+		next := func(_ context.Context, _ DynamoDBChange[item]) ([]byte, error) {
+			called = true
+			return nil, nil
+		}
+
+		_, err := DynamoDBImage(next)(context.TODO(), record("INSERT", nil, map[string]events.DynamoDBAttributeValue{"count": events.NewStringAttribute("x")}))
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+
+	t.Run("errors from next are propagated back", func(t *testing.T) {
+		expected := errors.New("expected")
+
+		next := func(_ context.Context, _ DynamoDBChange[item]) ([]byte, error) {
+			return nil, expected
+		}
+
+		_, err := DynamoDBStream(DynamoDBImage(next))(context.TODO(), events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{record("REMOVE", nil, nil)}})
+		assert.ErrorIs(t, err, expected)
+	})
+}
@@ -52,3 +52,60 @@ func TestDynamoDBStream(t *testing.T) {
 		assert.Nil(t, d)
 	})
 }
+
+func TestDynamoDBStreamBatchItemFailures(t *testing.T) {
+	t.Run("processing stops at the first failing record, and its sequence number is reported as the checkpoint", func(t *testing.T) {
+		in := events.DynamoDBEvent{
+			Records: []events.DynamoDBEventRecord{
+				{
+					EventID: "1",
+					Change:  events.DynamoDBStreamRecord{SequenceNumber: "100"},
+				},
+				{
+					EventID: "2",
+					Change:  events.DynamoDBStreamRecord{SequenceNumber: "200"},
+				},
+				{
+					EventID: "3",
+					Change:  events.DynamoDBStreamRecord{SequenceNumber: "300"},
+				},
+			},
+		}
+
+		var seen []string
+
+		next := func(_ context.Context, d events.DynamoDBEventRecord) ([]byte, error) {
+			seen = append(seen, d.EventID)
+
+			if d.EventID == "2" {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, nil
+		}
+
+		resp, err := DynamoDBStreamBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2"}, seen)
+		assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "200"}}, resp.BatchItemFailures)
+	})
+
+	t.Run("no failures results in an empty set of batch item failures", func(t *testing.T) {
+		in := events.DynamoDBEvent{
+			Records: []events.DynamoDBEventRecord{
+				{
+					EventID: "1",
+					Change:  events.DynamoDBStreamRecord{SequenceNumber: "100"},
+				},
+			},
+		}
+
+		next := func(_ context.Context, d events.DynamoDBEventRecord) ([]byte, error) {
+			return []byte(d.EventID), nil
+		}
+
+		resp, err := DynamoDBStreamBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Empty(t, resp.BatchItemFailures)
+	})
+}
@@ -0,0 +1,350 @@
+package lambdawrap
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DynamoDBUnmarshal decodes a DynamoDB item, such as the NewImage of a stream record, into v which must be a non-nil
+// pointer to a struct or a map with string keys.
+//
+// Struct fields are matched using a `dynamodbav:"name"` tag, or the field name if there is no tag, a tag of "-"
+// ignores the field. Anonymous struct fields without a tag have their fields treated as part of the outer struct.
+// Attributes without a matching field are ignored, and NULL attributes leave the field as its zero value.
+//
+// Attribute types are decoded as follows:
+//
+//   S        string, or a type implementing encoding.TextUnmarshaler such as time.Time
+//   N        int, uint and float types, string, or a type implementing encoding.TextUnmarshaler such as big.Int
+//   B        []byte
+//   BOOL     bool
+//   L        slice
+//   M        struct, or map with string keys
+//   SS/NS/BS slice, or map[T]bool / map[T]struct{} where T is string or a number for NS
+//
+// Any attribute may be decoded into an interface{}, in which case numbers are decoded as float64, L as []interface{},
+// M as map[string]interface{}, SS as []string, NS as []float64 and BS as [][]byte.
+func DynamoDBUnmarshal(item map[string]events.DynamoDBAttributeValue, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("DynamoDBUnmarshal requires a non-nil pointer")
+	}
+
+	return dynamoDBDecode(events.NewMapAttribute(item), rv.Elem())
+}
+
+var dynamoDBTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// dynamoDBDecode decodes a single attribute value into rv.
+func dynamoDBDecode(av events.DynamoDBAttributeValue, rv reflect.Value) error {
+	if av.IsNull() {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+
+		return dynamoDBDecode(av, rv.Elem())
+	}
+
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		if i, err := dynamoDBInterface(av); err != nil {
+			return err
+		} else {
+			rv.Set(reflect.ValueOf(i))
+			return nil
+		}
+	}
+
+	if (av.DataType() == events.DataTypeString || av.DataType() == events.DataTypeNumber) && reflect.PointerTo(rv.Type()).Implements(dynamoDBTextUnmarshaler) {
+		s := av.Number
+		if av.DataType() == events.DataTypeString {
+			s = av.String
+		}
+
+		return rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s()))
+	}
+
+	switch av.DataType() {
+	case events.DataTypeString:
+		if rv.Kind() == reflect.String {
+			rv.SetString(av.String())
+			return nil
+		}
+	case events.DataTypeNumber:
+		return dynamoDBDecodeNumber(av.Number(), rv)
+	case events.DataTypeBinary:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes(av.Binary())
+			return nil
+		}
+	case events.DataTypeBoolean:
+		if rv.Kind() == reflect.Bool {
+			rv.SetBool(av.Boolean())
+			return nil
+		}
+	case events.DataTypeList:
+		return dynamoDBDecodeList(av.List(), rv)
+	case events.DataTypeMap:
+		return dynamoDBDecodeMap(av.Map(), rv)
+	case events.DataTypeStringSet:
+		return dynamoDBDecodeSet(dynamoDBSetValues(av.StringSet(), events.NewStringAttribute), rv)
+	case events.DataTypeNumberSet:
+		return dynamoDBDecodeSet(dynamoDBSetValues(av.NumberSet(), events.NewNumberAttribute), rv)
+	case events.DataTypeBinarySet:
+		return dynamoDBDecodeSet(dynamoDBSetValues(av.BinarySet(), events.NewBinaryAttribute), rv)
+	}
+
+	return dynamoDBIncompatible(av, rv)
+}
+
+// dynamoDBDecodeNumber decodes a number attribute, which DynamoDB provides as a string.
+func dynamoDBDecodeNumber(n string, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(n, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(n, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(n, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("can not decode N into %s", rv.Type())
+	}
+
+	return nil
+}
+
+// dynamoDBDecodeList decodes a list attribute into a slice.
+func dynamoDBDecodeList(l []events.DynamoDBAttributeValue, rv reflect.Value) error {
+	if rv.Kind() != reflect.Slice {
+		return fmt.Errorf("can not decode L into %s", rv.Type())
+	}
+
+	s := reflect.MakeSlice(rv.Type(), len(l), len(l))
+
+	for i, av := range l {
+		if err := dynamoDBDecode(av, s.Index(i)); err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+	}
+
+	rv.Set(s)
+	return nil
+}
+
+// dynamoDBDecodeMap decodes a map attribute into a struct or a map with string keys.
+func dynamoDBDecodeMap(m map[string]events.DynamoDBAttributeValue, rv reflect.Value) error {
+	switch {
+	case rv.Kind() == reflect.Struct:
+		fields := dynamoDBFields(rv.Type())
+
+		for name, av := range m {
+			idx, ok := fields[name]
+			if !ok {
+				continue
+			}
+
+			f := dynamoDBField(rv, idx)
+
+			if err := dynamoDBDecode(av, f); err != nil {
+				return fmt.Errorf("attribute %q: %w", name, err)
+			}
+		}
+
+		return nil
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMapWithSize(rv.Type(), len(m)))
+		}
+
+		for name, av := range m {
+			e := reflect.New(rv.Type().Elem()).Elem()
+
+			if err := dynamoDBDecode(av, e); err != nil {
+				return fmt.Errorf("attribute %q: %w", name, err)
+			}
+
+			rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), e)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("can not decode M into %s", rv.Type())
+}
+
+// dynamoDBDecodeSet decodes the members of a set attribute into a slice, or into the keys of a map of bool or struct{}.
+func dynamoDBDecodeSet(members []events.DynamoDBAttributeValue, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Slice:
+		return dynamoDBDecodeList(members, rv)
+	case reflect.Map:
+		ev := reflect.ValueOf(true)
+		if k := rv.Type().Elem(); k.Kind() == reflect.Struct && k.NumField() == 0 {
+			ev = reflect.New(k).Elem()
+		} else if k.Kind() != reflect.Bool {
+			return fmt.Errorf("can not decode set into %s", rv.Type())
+		}
+
+		m := reflect.MakeMapWithSize(rv.Type(), len(members))
+
+		for _, av := range members {
+			k := reflect.New(rv.Type().Key()).Elem()
+
+			if err := dynamoDBDecode(av, k); err != nil {
+				return err
+			}
+
+			m.SetMapIndex(k, ev.Convert(rv.Type().Elem()))
+		}
+
+		rv.Set(m)
+		return nil
+	}
+
+	return fmt.Errorf("can not decode set into %s", rv.Type())
+}
+
+// dynamoDBSetValues converts the members of a set into attribute values, so they can be decoded individually.
+func dynamoDBSetValues[T any](members []T, f func(T) events.DynamoDBAttributeValue) []events.DynamoDBAttributeValue {
+	ret := make([]events.DynamoDBAttributeValue, len(members))
+
+	for i, m := range members {
+		ret[i] = f(m)
+	}
+
+	return ret
+}
+
+// dynamoDBInterface converts an attribute value into its natural Go type, for decoding into an interface{}.
+func dynamoDBInterface(av events.DynamoDBAttributeValue) (any, error) {
+	switch av.DataType() {
+	case events.DataTypeString:
+		return av.String(), nil
+	case events.DataTypeNumber:
+		return av.Float()
+	case events.DataTypeBinary:
+		return av.Binary(), nil
+	case events.DataTypeBoolean:
+		return av.Boolean(), nil
+	case events.DataTypeStringSet:
+		return av.StringSet(), nil
+	case events.DataTypeBinarySet:
+		return av.BinarySet(), nil
+	case events.DataTypeNumberSet:
+		var ret []float64
+		err := dynamoDBDecodeSet(dynamoDBSetValues(av.NumberSet(), events.NewNumberAttribute), reflect.ValueOf(&ret).Elem())
+		return ret, err
+	case events.DataTypeList:
+		var ret []any
+		err := dynamoDBDecodeList(av.List(), reflect.ValueOf(&ret).Elem())
+		return ret, err
+	case events.DataTypeMap:
+		var ret map[string]any
+		err := dynamoDBDecodeMap(av.Map(), reflect.ValueOf(&ret).Elem())
+		return ret, err
+	}
+
+	return nil, nil
+}
+
+// dynamoDBFields maps attribute names to the index of the struct field they populate, including the fields of
+// anonymous structs.
+func dynamoDBFields(t reflect.Type) map[string][]int {
+	ret := map[string][]int{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup("dynamodbav")
+		name := strings.Split(tag, ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if f.Anonymous && !tagged {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer && f.IsExported() {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				for n, idx := range dynamoDBFields(ft) {
+					if _, ok := ret[n]; !ok {
+						ret[n] = append([]int{i}, idx...)
+					}
+				}
+
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		ret[name] = []int{i}
+	}
+
+	return ret
+}
+
+// dynamoDBField returns the struct field at the index, allocating any nil anonymous struct pointers along the way.
+func dynamoDBField(rv reflect.Value, idx []int) reflect.Value {
+	for _, i := range idx[:len(idx)-1] {
+		rv = rv.Field(i)
+
+		if rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+
+			rv = rv.Elem()
+		}
+	}
+
+	return rv.Field(idx[len(idx)-1])
+}
+
+// dynamoDBIncompatible returns an error describing an attribute that can not be decoded into rv.
+func dynamoDBIncompatible(av events.DynamoDBAttributeValue, rv reflect.Value) error {
+	names := map[events.DynamoDBDataType]string{
+		events.DataTypeString:    "S",
+		events.DataTypeNumber:    "N",
+		events.DataTypeBinary:    "B",
+		events.DataTypeBoolean:   "BOOL",
+		events.DataTypeList:      "L",
+		events.DataTypeMap:       "M",
+		events.DataTypeStringSet: "SS",
+		events.DataTypeNumberSet: "NS",
+		events.DataTypeBinarySet: "BS",
+	}
+
+	return fmt.Errorf("can not decode %s into %s", names[av.DataType()], rv.Type())
+}
@@ -0,0 +1,149 @@
+package lambdawrap
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestDynamoDBUnmarshal(t *testing.T) {
+	type address struct {
+		Street string `dynamodbav:"street"`
+	}
+
+	type Audit struct {
+		CreatedBy string `dynamodbav:"createdBy"`
+	}
+
+	type item struct {
+		Audit
+		ID       string              `dynamodbav:"pk"`
+		Count    int                 `dynamodbav:"count"`
+		Small    int8                `dynamodbav:"small"`
+		Unsigned uint32              `dynamodbav:"unsigned"`
+		Price    float64             `dynamodbav:"price"`
+		Big      *big.Int            `dynamodbav:"big"`
+		BigFloat big.Float           `dynamodbav:"bigFloat"`
+		NumStr   string              `dynamodbav:"numStr"`
+		Data     []byte              `dynamodbav:"data"`
+		Active   bool                `dynamodbav:"active"`
+		Missing  *string             `dynamodbav:"missing"`
+		Tags     []string            `dynamodbav:"tags"`
+		TagSet   map[string]struct{} `dynamodbav:"tagSet"`
+		Scores   []int               `dynamodbav:"scores"`
+		ScoreSet map[int]bool        `dynamodbav:"scoreSet"`
+		Blobs    [][]byte            `dynamodbav:"blobs"`
+		Address  address             `dynamodbav:"address"`
+		Lines    []address           `dynamodbav:"lines"`
+		Attrs    map[string]string   `dynamodbav:"attrs"`
+		Any      interface{}         `dynamodbav:"any"`
+		Created  time.Time           `dynamodbav:"created"`
+		Ignored  string              `dynamodbav:"-"`
+		Untagged string
+	}
+
+	t.Run("decodes all attribute types into a struct", func(t *testing.T) {
+		in := map[string]events.DynamoDBAttributeValue{
+			"pk":        events.NewStringAttribute("id"),
+			"createdBy": events.NewStringAttribute("bob"),
+			"count":     events.NewNumberAttribute("42"),
+			"small":     events.NewNumberAttribute("-8"),
+			"unsigned":  events.NewNumberAttribute("7"),
+			"price":     events.NewNumberAttribute("1.5"),
+			"big":       events.NewNumberAttribute("123456789012345678901234567890"),
+			"bigFloat":  events.NewNumberAttribute("0.25"),
+			"numStr":    events.NewNumberAttribute("99"),
+			"data":      events.NewBinaryAttribute([]byte{1, 2}),
+			"active":    events.NewBooleanAttribute(true),
+			"missing":   events.NewNullAttribute(),
+			"tags":      events.NewStringSetAttribute([]string{"a", "b"}),
+			"tagSet":    events.NewStringSetAttribute([]string{"a", "b"}),
+			"scores":    events.NewNumberSetAttribute([]string{"1", "2"}),
+			"scoreSet":  events.NewNumberSetAttribute([]string{"3"}),
+			"blobs":     events.NewBinarySetAttribute([][]byte{{1}, {2}}),
+			"address":   events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"street": events.NewStringAttribute("High St")}),
+			"lines": events.NewListAttribute([]events.DynamoDBAttributeValue{
+				events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"street": events.NewStringAttribute("Low St")}),
+			}),
+			"attrs": events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"k": events.NewStringAttribute("v")}),
+			"any": events.NewListAttribute([]events.DynamoDBAttributeValue{
+				events.NewNumberAttribute("1"),
+				events.NewStringAttribute("s"),
+				events.NewMapAttribute(map[string]events.DynamoDBAttributeValue{"b": events.NewBooleanAttribute(true)}),
+			}),
+			"created":  events.NewStringAttribute("2022-01-02T03:04:05Z"),
+			"Ignored":  events.NewStringAttribute("x"),
+			"Untagged": events.NewStringAttribute("untagged"),
+			"unknown":  events.NewStringAttribute("x"),
+		}
+
+		var out item
+		err := DynamoDBUnmarshal(in, &out)
+		assert.NoError(t, err)
+
+		expectedBig, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+
+		assert.Equal(t, "id", out.ID)
+		assert.Equal(t, "bob", out.CreatedBy)
+		assert.Equal(t, 42, out.Count)
+		assert.Equal(t, int8(-8), out.Small)
+		assert.Equal(t, uint32(7), out.Unsigned)
+		assert.Equal(t, 1.5, out.Price)
+		assert.Equal(t, 0, expectedBig.Cmp(out.Big))
+		assert.Equal(t, "0.25", out.BigFloat.String())
+		assert.Equal(t, "99", out.NumStr)
+		assert.Equal(t, []byte{1, 2}, out.Data)
+		assert.True(t, out.Active)
+		assert.Nil(t, out.Missing)
+		assert.Equal(t, []string{"a", "b"}, out.Tags)
+		assert.Equal(t, map[string]struct{}{"a": {}, "b": {}}, out.TagSet)
+		assert.Equal(t, []int{1, 2}, out.Scores)
+		assert.Equal(t, map[int]bool{3: true}, out.ScoreSet)
+		assert.Equal(t, [][]byte{{1}, {2}}, out.Blobs)
+		assert.Equal(t, address{Street: "High St"}, out.Address)
+		assert.Equal(t, []address{{Street: "Low St"}}, out.Lines)
+		assert.Equal(t, map[string]string{"k": "v"}, out.Attrs)
+		assert.Equal(t, []interface{}{1.0, "s", map[string]interface{}{"b": true}}, out.Any)
+		assert.True(t, time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC).Equal(out.Created))
+		assert.Equal(t, "", out.Ignored)
+		assert.Equal(t, "untagged", out.Untagged)
+	})
+
+	t.Run("decodes into a map", func(t *testing.T) {
+		in := map[string]events.DynamoDBAttributeValue{
+			"a": events.NewStringAttribute("x"),
+			"b": events.NewNumberAttribute("2"),
+		}
+
+		var out map[string]interface{}
+		assert.NoError(t, DynamoDBUnmarshal(in, &out))
+		assert.Equal(t, map[string]interface{}{"a": "x", "b": 2.0}, out)
+	})
+
+	t.Run("returns an error for incompatible types, including the attribute name", func(t *testing.T) {
+		in := map[string]events.DynamoDBAttributeValue{
+			"count": events.NewStringAttribute("x"),
+		}
+
+		var out item
+		err := DynamoDBUnmarshal(in, &out)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "count")
+	})
+
+	t.Run("returns an error for numbers out of range", func(t *testing.T) {
+		in := map[string]events.DynamoDBAttributeValue{
+			"small": events.NewNumberAttribute("300"),
+		}
+
+		var out item
+		assert.Error(t, DynamoDBUnmarshal(in, &out))
+	})
+
+	t.Run("returns an error if not provided a pointer", func(t *testing.T) {
+		var out item
+		assert.Error(t, DynamoDBUnmarshal(nil, out))
+	})
+}
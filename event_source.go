@@ -0,0 +1,160 @@
+package lambdawrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrEventSourceUnknown is returned by DetectEventSource if no EventSource detects the event.
+var ErrEventSourceUnknown = errors.New("unknown event source")
+
+// EventSource pairs a function to detect the shape of a raw Lambda event, with a function to handle it. It should
+// usually be constructed with EventSourceOf.
+type EventSource struct {
+	// Detect returns true if the raw event is from this source.
+	Detect func(json.RawMessage) bool
+	// Handle processes the raw event.
+	Handle func(context.Context, json.RawMessage) ([]byte, error)
+}
+
+// EventSourceOf constructs an EventSource which unmarshals the raw event into E before calling n, typically n would be
+// one of the wraps such as SQS or EventBridge. If E is []byte or string the raw event is provided as is.
+//
+// Example:
+//
+//   EventSourceOf(DetectS3, S3Notification(S3Fetch(S3ReadAll(myFunc), fetcher)))
+func EventSourceOf[E any](detect func(json.RawMessage) bool, n func(context.Context, E) ([]byte, error)) EventSource {
+	return EventSource{
+		Detect: detect,
+		Handle: func(ctx context.Context, raw json.RawMessage) ([]byte, error) {
+			if e, err := sliceStringOrUnmarshal[E](raw); err != nil {
+				return nil, fmt.Errorf("EventSource unmarshal: %w", err)
+			} else {
+				return n(ctx, e)
+			}
+		},
+	}
+}
+
+// DetectEventSource provides a handler that takes the raw Lambda event, and calls the first EventSource that detects
+// it. This permits the same function to be deployed behind different event sources. If no EventSource detects the
+// event an error wrapping ErrEventSourceUnknown is returned.
+//
+// Example:
+//
+//   lambda.Start(DetectEventSource(
+//     EventSourceOf(DetectSQS, SQS(myFunc)),
+//     EventSourceOf(DetectEventBridge, EventBridge(myFunc)),
+//   ))
+func DetectEventSource(sources ...EventSource) func(context.Context, json.RawMessage) ([]byte, error) {
+	return func(ctx context.Context, raw json.RawMessage) ([]byte, error) {
+		for _, s := range sources {
+			if s.Detect(raw) {
+				return s.Handle(ctx, raw)
+			}
+		}
+
+		return nil, ErrEventSourceUnknown
+	}
+}
+
+// AutoDetect provides a handler that takes the raw Lambda event, and passes the message it carries to n regardless of
+// whether it was delivered by SQS (including SNS envelopes), SNS, Kinesis, EventBridge, or directly invoked with the
+// message as the payload. The sources provided are checked before the defaults, permitting S3, DynamoDB or custom
+// sources to be added.
+//
+// S3 and DynamoDB events do not carry a message, as an S3 object must be fetched and a DynamoDB record holds item
+// images, so there is no default route for them. Unless a source is provided for them, they result in an error
+// wrapping ErrEventSourceUnknown, rather than being passed to n as a direct invocation.
+//
+// Example:
+//
+//   lambda.Start(AutoDetect(DomainObject(SideEffect(myFunc), codec.JSON)))
+//
+//   lambda.Start(AutoDetect(DomainObject(SideEffect(myFunc), codec.JSON),
+//     EventSourceOf(DetectS3, S3Notification(S3Fetch(S3ReadAll(myFunc), fetcher))),
+//   ))
+func AutoDetect(n func(context.Context, []byte) ([]byte, error), sources ...EventSource) func(context.Context, json.RawMessage) ([]byte, error) {
+	defaults := []EventSource{
+		EventSourceOf(DetectSQS, SQS(SNSEnvelope(n))),
+		EventSourceOf(DetectSNS, SNS(n)),
+		EventSourceOf(DetectKinesis, Kinesis(n)),
+		EventSourceOf(DetectEventBridge, EventBridge(n)),
+		autoDetectUnrouted(DetectS3, "S3"),
+		autoDetectUnrouted(DetectDynamoDB, "DynamoDB"),
+		EventSourceOf(DetectPayload, n),
+	}
+
+	return DetectEventSource(append(append([]EventSource{}, sources...), defaults...)...)
+}
+
+// autoDetectUnrouted constructs an EventSource for events which AutoDetect has no default route for, preventing them
+// from being treated as a direct invocation.
+func autoDetectUnrouted(detect func(json.RawMessage) bool, name string) EventSource {
+	return EventSource{
+		Detect: detect,
+		Handle: func(_ context.Context, _ json.RawMessage) ([]byte, error) {
+			return nil, fmt.Errorf("AutoDetect %s event has no source: %w", name, ErrEventSourceUnknown)
+		},
+	}
+}
+
+// DetectSQS detects an events.SQSEvent.
+func DetectSQS(raw json.RawMessage) bool {
+	return detectRecordsSource(raw, "aws:sqs")
+}
+
+// DetectSNS detects an events.SNSEvent.
+func DetectSNS(raw json.RawMessage) bool {
+	return detectRecordsSource(raw, "aws:sns")
+}
+
+// DetectS3 detects an events.S3Event.
+func DetectS3(raw json.RawMessage) bool {
+	return detectRecordsSource(raw, "aws:s3")
+}
+
+// DetectDynamoDB detects an events.DynamoDBEvent.
+func DetectDynamoDB(raw json.RawMessage) bool {
+	return detectRecordsSource(raw, "aws:dynamodb")
+}
+
+// DetectKinesis detects an events.KinesisEvent.
+func DetectKinesis(raw json.RawMessage) bool {
+	return detectRecordsSource(raw, "aws:kinesis")
+}
+
+// DetectEventBridge detects an events.CloudWatchEvent delivered by EventBridge.
+func DetectEventBridge(raw json.RawMessage) bool {
+	var e events.CloudWatchEvent
+
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return false
+	}
+
+	return e.Source != "" && e.DetailType != "" && len(e.Detail) > 0
+}
+
+// DetectPayload detects any event, it should be the last EventSource and is used for direct invocation.
+func DetectPayload(_ json.RawMessage) bool {
+	return true
+}
+
+// detectRecordsSource checks if the raw event contains Records from the event source, SNS uses EventSource rather than
+// eventSource, which is matched as encoding/json is case-insensitive.
+func detectRecordsSource(raw json.RawMessage, source string) bool {
+	var e struct {
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		}
+	}
+
+	if err := json.Unmarshal(raw, &e); err != nil || len(e.Records) == 0 {
+		return false
+	}
+
+	return e.Records[0].EventSource == source
+}
@@ -0,0 +1,152 @@
+package lambdawrap
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	testSQSEvent         = `{"Records":[{"messageId":"1","body":"sqs","eventSource":"aws:sqs","eventSourceARN":"arn:aws:sqs:eu-west-1:123456789012:queue"}]}`
+	testSNSEvent         = `{"Records":[{"EventSource":"aws:sns","Sns":{"Type":"Notification","TopicArn":"arn:aws:sns:eu-west-1:123456789012:topic","Message":"sns"}}]}`
+	testS3Event          = `{"Records":[{"eventSource":"aws:s3","s3":{"bucket":{"name":"bucket"},"object":{"key":"my+key"}}}]}`
+	testDynamoDBEvent    = `{"Records":[{"eventSource":"aws:dynamodb","eventName":"INSERT","dynamodb":{"Keys":{"pk":{"S":"1"}}}}]}`
+	testKinesisEvent     = `{"Records":[{"eventSource":"aws:kinesis","kinesis":{"partitionKey":"p","data":"a2luZXNpcw=="}}]}`
+	testEventBridgeEvent = `{"version":"0","id":"1","detail-type":"OrderCreated","source":"com.example","detail":"eventbridge"}`
+)
+
+func TestDetect(t *testing.T) {
+	detectors := map[string]func(json.RawMessage) bool{
+		"sqs":         DetectSQS,
+		"sns":         DetectSNS,
+		"s3":          DetectS3,
+		"dynamodb":    DetectDynamoDB,
+		"kinesis":     DetectKinesis,
+		"eventbridge": DetectEventBridge,
+	}
+
+	inputs := map[string]string{
+		"sqs":         testSQSEvent,
+		"sns":         testSNSEvent,
+		"s3":          testS3Event,
+		"dynamodb":    testDynamoDBEvent,
+		"kinesis":     testKinesisEvent,
+		"eventbridge": testEventBridgeEvent,
+		"payload":     `{"id":"1"}`,
+		"array":       `[1,2,3]`,
+		"text":        `"text"`,
+	}
+
+	for dName, detect := range detectors {
+		for iName, in := range inputs {
+			assert.Equal(t, dName == iName, detect(json.RawMessage(in)), "detector %s input %s", dName, iName)
+		}
+	}
+
+	for _, in := range inputs {
+		assert.True(t, DetectPayload(json.RawMessage(in)))
+	}
+}
+
+func TestDetectEventSource(t *testing.T) {
+	t.Run("the first source to detect the event handles it", func(t *testing.T) {
+		f := DetectEventSource(
+			EventSourceOf(DetectS3, S3Notification(func(_ context.Context, e events.S3EventRecord) ([]byte, error) {
+				return []byte(e.S3.Object.URLDecodedKey), nil
+			})),
+			EventSourceOf(DetectPayload, func(_ context.Context, d []byte) ([]byte, error) {
+				return []byte("payload"), nil
+			}),
+		)
+
+		d, err := f(context.TODO(), json.RawMessage(testS3Event))
+		assert.NoError(t, err)
+		assert.Equal(t, "my key", string(d))
+
+		d, err = f(context.TODO(), json.RawMessage(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "payload", string(d))
+	})
+
+	t.Run("returns an error if no source detects the event", func(t *testing.T) {
+		_, err := DetectEventSource(EventSourceOf(DetectSQS, SQS(Nop[[]byte]())))(context.TODO(), json.RawMessage(testSNSEvent))
+		assert.ErrorIs(t, err, ErrEventSourceUnknown)
+	})
+
+	t.Run("returns an error if the event can not be unmarshalled", func(t *testing.T) {
+		_, err := DetectEventSource(EventSourceOf(DetectPayload, func(_ context.Context, _ struct{ ID int }) ([]byte, error) {
+			return nil, nil
+		}))(context.TODO(), json.RawMessage(`{"ID":"x"}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestAutoDetect(t *testing.T) {
+	next := func(_ context.Context, d []byte) ([]byte, error) {
+		return d, nil
+	}
+
+	t.Run("the message is provided to next regardless of source", func(t *testing.T) {
+		envelope := `{"Type":"Notification","TopicArn":"arn:aws:sns:eu-west-1:123456789012:topic","Message":"sns via sqs"}`
+		sqsWithSNS, _ := json.Marshal(events.SQSEvent{Records: []events.SQSMessage{{EventSource: "aws:sqs", Body: envelope}}})
+
+		for in, expected := range map[string]string{
+			testSQSEvent:         "sqs",
+			string(sqsWithSNS):   "sns via sqs",
+			testSNSEvent:         "sns",
+			testKinesisEvent:     "kinesis",
+			testEventBridgeEvent: `"eventbridge"`,
+			`{"id":"1"}`:         `{"id":"1"}`,
+		} {
+			d, err := AutoDetect(next)(context.TODO(), json.RawMessage(in))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, string(d))
+		}
+	})
+
+	t.Run("sources provided are checked before the defaults", func(t *testing.T) {
+		custom := EventSourceOf(DetectSQS, func(_ context.Context, _ events.SQSEvent) ([]byte, error) {
+			return []byte("custom"), nil
+		})
+
+		d, err := AutoDetect(next, custom)(context.TODO(), json.RawMessage(testSQSEvent))
+		assert.NoError(t, err)
+		assert.Equal(t, "custom", string(d))
+	})
+
+	t.Run("S3 and DynamoDB events without a source are not provided to next", func(t *testing.T) {
+		called := false
+
+		n := func(_ context.Context, _ []byte) ([]byte, error) {
+			called = true
+			return nil, nil
+		}
+
+		for _, in := range []string{testS3Event, testDynamoDBEvent} {
+			_, err := AutoDetect(n)(context.TODO(), json.RawMessage(in))
+			assert.ErrorIs(t, err, ErrEventSourceUnknown)
+		}
+
+		assert.False(t, called)
+	})
+
+	t.Run("S3 and DynamoDB events are handled by the sources provided", func(t *testing.T) {
+		s3 := EventSourceOf(DetectS3, S3Notification(func(_ context.Context, r events.S3EventRecord) ([]byte, error) {
+			return []byte(r.S3.Bucket.Name), nil
+		}))
+
+		dynamo := EventSourceOf(DetectDynamoDB, DynamoDBStream(func(_ context.Context, r events.DynamoDBEventRecord) ([]byte, error) {
+			return []byte(r.EventName), nil
+		}))
+
+		d, err := AutoDetect(next, s3, dynamo)(context.TODO(), json.RawMessage(testS3Event))
+		assert.NoError(t, err)
+		assert.Equal(t, "bucket", string(d))
+
+		d, err = AutoDetect(next, s3, dynamo)(context.TODO(), json.RawMessage(testDynamoDBEvent))
+		assert.NoError(t, err)
+		assert.Equal(t, "INSERT", string(d))
+	})
+}
@@ -0,0 +1,78 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrEventBridgeNoRoute is returned by EventBridgeRouter if no route matches an event and no fallback is provided.
+var ErrEventBridgeNoRoute = errors.New("no eventbridge route")
+
+// EventBridge provides a wrapper to handle an events.CloudWatchEvent delivered by EventBridge (or CloudWatch Events),
+// providing the detail of the event to next.
+//
+// EventBridge will attempt to unmarshal any destination structure with JSON, in the same way as SQS. It is recommended
+// you use DomainObject instead, a Codec can be provided to support decoding the detail into a domain object.
+//
+// The event, including its source, detail-type, account, region, resources and ID, is added to the context and can be
+// extracted with EventBridgeEventFromContext.
+func EventBridge[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, events.CloudWatchEvent) ([]byte, error) {
+	return func(ctx context.Context, e events.CloudWatchEvent) ([]byte, error) {
+		ctx = context.WithValue(ctx, contextKeyEventBridgeEvent, e)
+
+		if p, err := sliceStringOrUnmarshal[O](e.Detail); err != nil {
+			return nil, fmt.Errorf("EventBridge unmarshal: %w", err)
+		} else {
+			if d, err := n(ctx, p); err != nil {
+				return nil, fmt.Errorf("EventBridge next: %w", err)
+			} else {
+				return d, nil
+			}
+		}
+	}
+}
+
+// EventBridgeRoute identifies a type of event handled by EventBridgeRouter. If DetailType is empty the route matches
+// any event from Source that does not have a more specific route.
+type EventBridgeRoute struct {
+	// Source is the source of the event, e.g. aws.ec2.
+	Source string
+	// DetailType is the detail-type of the event, e.g. EC2 Instance State-change Notification.
+	DetailType string
+}
+
+// EventBridgeRouter allows a single Lambda to handle multiple types of event, selecting the function to call based upon
+// the source and detail-type of the event. If no route matches, fallback is called, if fallback is nil an error
+// wrapping ErrEventBridgeNoRoute is returned. Nop can be used as the fallback to ignore unknown events.
+//
+// Example:
+//
+//   EventBridgeRouter(map[EventBridgeRoute]func(context.Context, events.CloudWatchEvent) ([]byte, error){
+//     {Source: "com.example.orders", DetailType: "OrderCreated"}: EventBridge(DomainObject(SideEffect(orderCreated), codec.JSON)),
+//     {Source: "com.example.orders", DetailType: "OrderCancelled"}: EventBridge(DomainObject(SideEffect(orderCancelled), codec.JSON)),
+//   }, Nop[events.CloudWatchEvent]())
+func EventBridgeRouter(m map[EventBridgeRoute]func(context.Context, events.CloudWatchEvent) ([]byte, error), fallback func(context.Context, events.CloudWatchEvent) ([]byte, error)) func(context.Context, events.CloudWatchEvent) ([]byte, error) {
+	return func(ctx context.Context, e events.CloudWatchEvent) ([]byte, error) {
+		if fn, ok := m[EventBridgeRoute{Source: e.Source, DetailType: e.DetailType}]; ok {
+			return fn(ctx, e)
+		} else if fn, ok := m[EventBridgeRoute{Source: e.Source}]; ok {
+			return fn(ctx, e)
+		} else if fallback != nil {
+			return fallback(ctx, e)
+		} else {
+			return nil, fmt.Errorf("%w: source %q detail-type %q", ErrEventBridgeNoRoute, e.Source, e.DetailType)
+		}
+	}
+}
+
+// EventBridgeEventFromContext retrieves an events.CloudWatchEvent from the context, for use after an EventBridge wrap
+// has been used if the application needs the source, detail-type or other details of the event.
+func EventBridgeEventFromContext(ctx context.Context) (events.CloudWatchEvent, bool) {
+	if val := ctx.Value(contextKeyEventBridgeEvent); val != nil {
+		return val.(events.CloudWatchEvent), true
+	} else {
+		return events.CloudWatchEvent{}, false
+	}
+}
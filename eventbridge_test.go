@@ -0,0 +1,106 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pwood/lambdawrap/codec"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestEventBridge(t *testing.T) {
+	type detail struct {
+		Val string
+	}
+
+	t.Run("the detail is decoded, next is called and the event is available on the context", func(t *testing.T) {
+		in := events.CloudWatchEvent{
+			ID:         "id",
+			Source:     "com.example",
+			DetailType: "Example",
+			AccountID:  "123456789012",
+			Region:     "eu-west-1",
+			Resources:  []string{"arn"},
+			Detail:     []byte(`{"Val":"1"}`),
+		}
+
+		next := func(ctx context.Context, d detail) error {
+			e, ok := EventBridgeEventFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "id", e.ID)
+			assert.Equal(t, "com.example", e.Source)
+			assert.Equal(t, "Example", e.DetailType)
+			assert.Equal(t, "123456789012", e.AccountID)
+			assert.Equal(t, "eu-west-1", e.Region)
+			assert.Equal(t, []string{"arn"}, e.Resources)
+			assert.Equal(t, "1", d.Val)
+
+			return nil
+		}
+
+		d, err := EventBridge(DomainObject(SideEffect(next), codec.JSON))(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("null"), d)
+	})
+
+	t.Run("unmarshalling a detail with a JSON error will result in an error", func(t *testing.T) {
+		next := func(_ context.Context, d detail) ([]byte, error) {
+			t.Fatal("next called unexpectedly")
+			return nil, nil
+		}
+
+		d, err := EventBridge(next)(context.TODO(), events.CloudWatchEvent{Detail: []byte(`{"Val":`)})
+		assert.Error(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("an error from next will result in an error", func(t *testing.T) {
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		d, err := EventBridge(next)(context.TODO(), events.CloudWatchEvent{Detail: []byte(`{}`)})
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.Nil(t, d)
+	})
+}
+
+func TestEventBridgeRouter(t *testing.T) {
+	respond := func(s string) func(context.Context, events.CloudWatchEvent) ([]byte, error) {
+		return func(_ context.Context, _ events.CloudWatchEvent) ([]byte, error) {
+			return []byte(s), nil
+		}
+	}
+
+	routes := map[EventBridgeRoute]func(context.Context, events.CloudWatchEvent) ([]byte, error){
+		{Source: "orders", DetailType: "Created"}: respond("created"),
+		{Source: "orders", DetailType: "Deleted"}: respond("deleted"),
+		{Source: "customers"}:                     respond("customers"),
+	}
+
+	t.Run("the route matching source and detail-type is called", func(t *testing.T) {
+		d, err := EventBridgeRouter(routes, nil)(context.TODO(), events.CloudWatchEvent{Source: "orders", DetailType: "Deleted"})
+		assert.NoError(t, err)
+		assert.Equal(t, "deleted", string(d))
+	})
+
+	t.Run("a route with only a source matches any detail-type", func(t *testing.T) {
+		d, err := EventBridgeRouter(routes, nil)(context.TODO(), events.CloudWatchEvent{Source: "customers", DetailType: "Updated"})
+		assert.NoError(t, err)
+		assert.Equal(t, "customers", string(d))
+	})
+
+	t.Run("the fallback is called if no route matches", func(t *testing.T) {
+		d, err := EventBridgeRouter(routes, respond("fallback"))(context.TODO(), events.CloudWatchEvent{Source: "orders", DetailType: "Updated"})
+		assert.NoError(t, err)
+		assert.Equal(t, "fallback", string(d))
+	})
+
+	t.Run("an error is returned if no route matches and there is no fallback", func(t *testing.T) {
+		d, err := EventBridgeRouter(routes, nil)(context.TODO(), events.CloudWatchEvent{Source: "orders", DetailType: "Updated"})
+		assert.True(t, errors.Is(err, ErrEventBridgeNoRoute))
+		assert.Nil(t, d)
+	})
+}
@@ -53,3 +53,16 @@ func Switch[O any, I comparable](f func(O) I, m map[I]func(context.Context, O) (
 		}
 	}
 }
+
+// SwitchContext is the same as Switch, however the function provided also receives the context. This permits switching
+// based upon values added to the context by earlier wraps, such as SNS message attributes.
+func SwitchContext[O any, I comparable](f func(context.Context, O) I, m map[I]func(context.Context, O) ([]byte, error)) func(context.Context, O) ([]byte, error) {
+	return func(ctx context.Context, o O) ([]byte, error) {
+		s := f(ctx, o)
+		if fn, ok := m[s]; ok {
+			return fn(ctx, o)
+		} else {
+			return nil, fmt.Errorf("no select match: %v", s)
+		}
+	}
+}
@@ -149,3 +149,32 @@ func TestSwitch(t *testing.T) {
 		assert.Equal(t, io.ErrUnexpectedEOF, err)
 	})
 }
+
+func TestSwitchContext(t *testing.T) {
+	type key string
+
+	selector := func(ctx context.Context, _ string) string {
+		return ctx.Value(key("k")).(string)
+	}
+
+	t.Run("returns an error if no match is found", func(t *testing.T) {
+		_, err := SwitchContext[string, string](selector, map[string]func(context.Context, string) ([]byte, error){
+			"a": func(ctx context.Context, s string) ([]byte, error) {
+				return nil, nil
+			},
+		})(context.WithValue(context.TODO(), key("k"), "b"), "")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("returns data from function in map selected using the context", func(t *testing.T) {
+		d, err := SwitchContext[string, string](selector, map[string]func(context.Context, string) ([]byte, error){
+			"a": func(ctx context.Context, s string) ([]byte, error) {
+				return []byte("data"), nil
+			},
+		})(context.WithValue(context.TODO(), key("k"), "a"), "")
+
+		assert.Equal(t, []byte("data"), d)
+		assert.NoError(t, err)
+	})
+}
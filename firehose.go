@@ -0,0 +1,67 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrFirehoseDropped can be returned by any function chained from Firehose to indicate that the record should be
+// marked as Dropped, rather than ProcessingFailed.
+var ErrFirehoseDropped = errors.New("firehose record dropped")
+
+// Firehose provides a wrapper to transform each record included in an events.KinesisFirehoseEvent, returning an
+// events.KinesisFirehoseResponse with a result for every record ID. The []byte output from next becomes the transformed
+// data of the record, this is base64 encoded automatically when the response is marshalled.
+//
+// The result of each record is determined by next:
+//
+//   - an error wrapping ErrFirehoseDropped, or a nil []byte with no error, marks the record as Dropped
+//   - any other error, or a failure to unmarshal, marks the record as ProcessingFailed
+//   - otherwise the record is marked as Ok
+//
+// As Filter and Nop return a nil []byte, records which fail a Filter are Dropped.
+//
+// Firehose will attempt to unmarshal any destination structure with JSON in the same way as SQS. It is recommended you
+// use DomainObject instead.
+//
+// The record ID is added to the context, and can be extracted with FirehoseRecordIDFromContext.
+func Firehose[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, events.KinesisFirehoseEvent) (events.KinesisFirehoseResponse, error) {
+	return func(ctx context.Context, e events.KinesisFirehoseEvent) (events.KinesisFirehoseResponse, error) {
+		ret := events.KinesisFirehoseResponse{Records: make([]events.KinesisFirehoseResponseRecord, 0, len(e.Records))}
+
+		for _, r := range e.Records {
+			rCtx := context.WithValue(ctx, contextKeyFirehoseRecordID, r.RecordID)
+			out := events.KinesisFirehoseResponseRecord{RecordID: r.RecordID}
+
+			if p, err := sliceStringOrUnmarshal[O](r.Data); err != nil {
+				out.Result = events.KinesisFirehoseTransformedStateProcessingFailed
+				out.Data = r.Data
+			} else if d, err := n(rCtx, p); errors.Is(err, ErrFirehoseDropped) {
+				out.Result = events.KinesisFirehoseTransformedStateDropped
+			} else if err != nil {
+				out.Result = events.KinesisFirehoseTransformedStateProcessingFailed
+				out.Data = r.Data
+			} else if d == nil {
+				out.Result = events.KinesisFirehoseTransformedStateDropped
+			} else {
+				out.Result = events.KinesisFirehoseTransformedStateOk
+				out.Data = d
+			}
+
+			ret.Records = append(ret.Records, out)
+		}
+
+		return ret, nil
+	}
+}
+
+// FirehoseRecordIDFromContext retrieves a Firehose record ID from the context, for use after a Firehose wrap has been
+// used if the application needs the ID of the record being transformed.
+func FirehoseRecordIDFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyFirehoseRecordID); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
@@ -0,0 +1,100 @@
+package lambdawrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFirehose(t *testing.T) {
+	t.Run("each record is transformed by next and reported as Ok with the transformed data", func(t *testing.T) {
+		in := events.KinesisFirehoseEvent{
+			Records: []events.KinesisFirehoseEventRecord{
+				{
+					RecordID: "1",
+					Data:     []byte("a"),
+				},
+				{
+					RecordID: "2",
+					Data:     []byte("b"),
+				},
+			},
+		}
+
+		next := func(ctx context.Context, d string) ([]byte, error) {
+			recordID, ok := FirehoseRecordIDFromContext(ctx)
+			assert.True(t, ok)
+
+			return []byte(recordID + strings.ToUpper(d)), nil
+		}
+
+		resp, err := Firehose(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []events.KinesisFirehoseResponseRecord{
+			{RecordID: "1", Result: events.KinesisFirehoseTransformedStateOk, Data: []byte("1A")},
+			{RecordID: "2", Result: events.KinesisFirehoseTransformedStateOk, Data: []byte("2B")},
+		}, resp.Records)
+	})
+
+	t.Run("records are marked as Dropped if next returns ErrFirehoseDropped or a nil []byte", func(t *testing.T) {
+		in := events.KinesisFirehoseEvent{
+			Records: []events.KinesisFirehoseEventRecord{
+				{
+					RecordID: "1",
+					Data:     []byte("drop"),
+				},
+				{
+					RecordID: "2",
+					Data:     []byte("filter"),
+				},
+			},
+		}
+
+		next := Filter(func(_ context.Context, d string) (bool, error) {
+			return d != "filter", nil
+		}, func(_ context.Context, d string) ([]byte, error) {
+			return nil, fmt.Errorf("wrapped: %w", ErrFirehoseDropped)
+		})
+
+		resp, err := Firehose(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []events.KinesisFirehoseResponseRecord{
+			{RecordID: "1", Result: events.KinesisFirehoseTransformedStateDropped},
+			{RecordID: "2", Result: events.KinesisFirehoseTransformedStateDropped},
+		}, resp.Records)
+	})
+
+	t.Run("records are marked as ProcessingFailed with their original data if unmarshal or next fails", func(t *testing.T) {
+		type myStruct struct {
+			Val string
+		}
+
+		in := events.KinesisFirehoseEvent{
+			Records: []events.KinesisFirehoseEventRecord{
+				{
+					RecordID: "1",
+					Data:     []byte("{\"val\": \"1\""),
+				},
+				{
+					RecordID: "2",
+					Data:     []byte("{\"val\": \"2\"}"),
+				},
+			},
+		}
+
+		next := func(_ context.Context, d myStruct) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		resp, err := Firehose(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []events.KinesisFirehoseResponseRecord{
+			{RecordID: "1", Result: events.KinesisFirehoseTransformedStateProcessingFailed, Data: []byte("{\"val\": \"1\"")},
+			{RecordID: "2", Result: events.KinesisFirehoseTransformedStateProcessingFailed, Data: []byte("{\"val\": \"2\"}")},
+		}, resp.Records)
+	})
+}
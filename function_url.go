@@ -0,0 +1,34 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// FunctionURL provides a wrapper to handle an events.LambdaFunctionURLRequest from a Lambda Function URL, in the same
+// manner as APIGatewayV2HTTP.
+func FunctionURL(n func(context.Context, []byte) ([]byte, error), opts ...HTTPOption) func(context.Context, events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	return func(ctx context.Context, e events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+		path, rawPath := httpPath(e.RawPath, "")
+
+		req := HTTPRequest{
+			Method:  e.RequestContext.HTTP.Method,
+			Path:    path,
+			RawPath: rawPath,
+			Headers: httpV2Headers(e.Headers, e.Cookies),
+			Query:   httpV2Query(e.RawQueryString, e.QueryStringParameters),
+		}
+
+		body, err := httpDecodeBody(e.Body, e.IsBase64Encoded)
+		resp, d := httpHandle(ctx, req, body, httpNext(n, err), opts)
+		encoded, isBase64Encoded := httpEncodeBody(d)
+
+		return events.LambdaFunctionURLResponse{
+			StatusCode:      resp.StatusCode,
+			Headers:         httpSingleValueHeaders(resp.Headers),
+			Body:            encoded,
+			IsBase64Encoded: isBase64Encoded,
+			Cookies:         resp.Headers.Values("Set-Cookie"),
+		}, nil
+	}
+}
@@ -0,0 +1,54 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestFunctionURL(t *testing.T) {
+	t.Run("the request is provided on the context, the body is passed to next and the output is returned as the response", func(t *testing.T) {
+		req := events.LambdaFunctionURLRequest{
+			RawPath:        "/orders",
+			RawQueryString: "a=1",
+			Headers:        map[string]string{"x-request-id": "abc"},
+			Body:           "body",
+			RequestContext: events.LambdaFunctionURLRequestContext{
+				HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: "PUT"},
+			},
+		}
+
+		next := func(ctx context.Context, d []byte) ([]byte, error) {
+			r, ok := HTTPRequestFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "PUT", r.Method)
+			assert.Equal(t, "/orders", r.Path)
+			assert.Equal(t, "abc", r.Headers.Get("X-Request-Id"))
+			assert.Equal(t, "1", r.Query.Get("a"))
+
+			resp, _ := HTTPResponseFromContext(ctx)
+			resp.StatusCode = http.StatusAccepted
+
+			return d, nil
+		}
+
+		resp, err := FunctionURL(next)(context.TODO(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+		assert.Equal(t, "body", resp.Body)
+		assert.False(t, resp.IsBase64Encoded)
+	})
+
+	t.Run("an HTTPError from next sets the status code", func(t *testing.T) {
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return nil, NewHTTPError(http.StatusForbidden, nil)
+		}
+
+		resp, err := FunctionURL(next)(context.TODO(), events.LambdaFunctionURLRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "Forbidden", resp.Body)
+	})
+}
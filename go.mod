@@ -3,9 +3,9 @@ module github.com/pwood/lambdawrap
 go 1.18
 
 require (
-	github.com/aws/aws-lambda-go v1.28.0
-	github.com/stretchr/testify v1.6.1
-	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	github.com/aws/aws-lambda-go v1.38.0
+	github.com/stretchr/testify v1.7.2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
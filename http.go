@@ -0,0 +1,242 @@
+package lambdawrap
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// ContentTyper may be implemented by a Codec to provide the MIME type of its encoding, this is used by HTTPDomainObject
+// to set the Content-Type of the response.
+type ContentTyper interface {
+	// ContentType returns the MIME type of the encoding.
+	ContentType() string
+}
+
+// HTTPRequest is a normalised representation of an HTTP request received by APIGatewayProxy, APIGatewayV2HTTP,
+// FunctionURL or ALB. The body of the request is provided to the next function, rather than being included here.
+type HTTPRequest struct {
+	// Method is the HTTP method of the request, e.g. GET.
+	Method string
+	// Path is the URL decoded path of the request, without any query string or API Gateway stage.
+	Path string
+	// RawPath is the path as received, with escaped characters such as %2F retained, so that it can be split into
+	// segments before decoding. It is empty for APIGatewayProxy, as API Gateway REST APIs only provide the decoded path.
+	RawPath string
+	// Headers contains the request headers, including any cookies as a Cookie header.
+	Headers http.Header
+	// Query contains the parsed query string of the request.
+	Query url.Values
+	// PathParameters contains any path parameters extracted by the event source.
+	PathParameters map[string]string
+}
+
+// HTTPResponse contains the status code and headers that will be returned with the response body. It is added to the
+// context by the HTTP wraps, and may be modified by any function chained from them via HTTPResponseFromContext.
+type HTTPResponse struct {
+	// StatusCode is the HTTP status code of the response, it defaults to 200.
+	StatusCode int
+	// Headers are the HTTP headers of the response.
+	Headers http.Header
+}
+
+// HTTPOption configures the HTTP wraps (APIGatewayProxy, APIGatewayV2HTTP, FunctionURL and ALB).
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	errorHook func(context.Context, error)
+}
+
+// WithHTTPErrorHook calls f with any error returned by the next function, before the response is returned to the
+// client. As the error is replaced by a status code in the response, this permits it to be logged or recorded. The
+// context contains the HTTPRequest and the HTTPResponse, which already has the status code for the error set.
+func WithHTTPErrorHook(f func(context.Context, error)) HTTPOption {
+	return func(o *httpOptions) {
+		o.errorHook = f
+	}
+}
+
+// HTTPError can be implemented by errors returned from functions chained from the HTTP wraps, to control the status
+// code returned to the client. Errors which do not implement HTTPError result in a 500 Internal Server Error. The body
+// of the response is the standard status text of the code, the error itself is not returned to the client.
+type HTTPError interface {
+	error
+	// HTTPStatusCode returns the HTTP status code that should be returned to the client.
+	HTTPStatusCode() int
+}
+
+// NewHTTPError returns an error that implements HTTPError, wrapping err. If err is nil, the standard status text is
+// used as the error message.
+func NewHTTPError(statusCode int, err error) error {
+	return httpError{statusCode: statusCode, err: err}
+}
+
+type httpError struct {
+	statusCode int
+	err        error
+}
+
+func (e httpError) Error() string {
+	if e.err == nil {
+		return http.StatusText(e.statusCode)
+	}
+
+	return e.err.Error()
+}
+
+func (e httpError) Unwrap() error {
+	return e.err
+}
+
+func (e httpError) HTTPStatusCode() int {
+	return e.statusCode
+}
+
+// HTTPDomainObject provides an automated approach to unmarshalling the body of an HTTP request into an input domain
+// object, and then marshalling the output domain object as the response body. It behaves as DomainObject, however an
+// empty request body results in the zero value of I, a failure to unmarshal results in a 400 Bad Request, and if the
+// Codec implements ContentTyper the Content-Type of the response is set.
+//
+// Example:
+//
+//   APIGatewayProxy(HTTPDomainObject(myFunc, codec.JSON))
+func HTTPDomainObject[I any, O any](n func(context.Context, I) (O, error), c Codec) func(context.Context, []byte) ([]byte, error) {
+	return func(ctx context.Context, d []byte) ([]byte, error) {
+		in := new(I)
+
+		if len(d) > 0 {
+			if err := c.Unmarshal(d, in); err != nil {
+				return nil, NewHTTPError(http.StatusBadRequest, fmt.Errorf("HTTPDomainObject codec unmarshal failure: %w", err))
+			}
+		}
+
+		ret, err := n(ctx, *in)
+		if err != nil {
+			return nil, fmt.Errorf("HTTPDomainObject next: %w", err)
+		}
+
+		data, err := c.Marshal(ret)
+		if err != nil {
+			return nil, fmt.Errorf("HTTPDomainObject codec marshal failure: %w", err)
+		}
+
+		if ct, ok := c.(ContentTyper); ok {
+			if resp, ok := HTTPResponseFromContext(ctx); ok && resp.Headers.Get("Content-Type") == "" {
+				resp.Headers.Set("Content-Type", ct.ContentType())
+			}
+		}
+
+		return data, nil
+	}
+}
+
+// HTTPRequestFromContext retrieves the HTTPRequest from the context, for use after an HTTP wrap has been used if the
+// application needs the method, path, headers or query string of the request.
+func HTTPRequestFromContext(ctx context.Context) (HTTPRequest, bool) {
+	if val := ctx.Value(contextKeyHTTPRequest); val != nil {
+		return val.(HTTPRequest), true
+	} else {
+		return HTTPRequest{}, false
+	}
+}
+
+// HTTPResponseFromContext retrieves the HTTPResponse from the context, for use after an HTTP wrap has been used if the
+// application needs to change the status code or headers of the response.
+func HTTPResponseFromContext(ctx context.Context) (*HTTPResponse, bool) {
+	if val := ctx.Value(contextKeyHTTPResponse); val != nil {
+		return val.(*HTTPResponse), true
+	} else {
+		return nil, false
+	}
+}
+
+// httpHandle calls next with the request body, mapping any error to an HTTP status code, and returns the response
+// along with the body to return to the client.
+func httpHandle(ctx context.Context, req HTTPRequest, body []byte, n func(context.Context, []byte) ([]byte, error), opts []HTTPOption) (*HTTPResponse, []byte) {
+	var o httpOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	resp := &HTTPResponse{StatusCode: http.StatusOK, Headers: http.Header{}}
+
+	ctx = context.WithValue(ctx, contextKeyHTTPRequest, req)
+	ctx = context.WithValue(ctx, contextKeyHTTPResponse, resp)
+
+	d, err := n(ctx, body)
+	if err != nil {
+		resp.StatusCode = http.StatusInternalServerError
+		d = []byte(http.StatusText(http.StatusInternalServerError))
+
+		var he HTTPError
+		if errors.As(err, &he) {
+			resp.StatusCode = he.HTTPStatusCode()
+			d = []byte(http.StatusText(resp.StatusCode))
+		}
+
+		resp.Headers.Set("Content-Type", "text/plain; charset=utf-8")
+
+		if o.errorHook != nil {
+			o.errorHook(ctx, err)
+		}
+	} else if len(d) > 0 && resp.Headers.Get("Content-Type") == "" {
+		resp.Headers.Set("Content-Type", http.DetectContentType(d))
+	}
+
+	return resp, d
+}
+
+// httpDecodeBody decodes a request body, which may be base64 encoded by the event source.
+func httpDecodeBody(body string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(body), nil
+	}
+
+	if d, err := base64.StdEncoding.DecodeString(body); err != nil {
+		return nil, NewHTTPError(http.StatusBadRequest, fmt.Errorf("base64 body decode: %w", err))
+	} else {
+		return d, nil
+	}
+}
+
+// httpEncodeBody encodes a response body, bodies which are not valid UTF-8 are base64 encoded.
+func httpEncodeBody(d []byte) (string, bool) {
+	if utf8.Valid(d) {
+		return string(d), false
+	}
+
+	return base64.StdEncoding.EncodeToString(d), true
+}
+
+// httpNext returns next, or if the request body could not be decoded a function which returns that error.
+func httpNext(n func(context.Context, []byte) ([]byte, error), err error) func(context.Context, []byte) ([]byte, error) {
+	if err != nil {
+		return Err[[]byte](err)
+	}
+
+	return n
+}
+
+// httpSingleValueHeaders converts headers to the single value form used by API Gateway, multiple values are joined
+// with a comma as permitted by RFC 7230. Set-Cookie headers are excluded, as they can not be joined.
+func httpSingleValueHeaders(h http.Header) map[string]string {
+	ret := make(map[string]string, len(h))
+
+	for k, v := range h {
+		if k == "Set-Cookie" {
+			continue
+		}
+
+		ret[k] = v[0]
+		for _, s := range v[1:] {
+			ret[k] += "," + s
+		}
+	}
+
+	return ret
+}
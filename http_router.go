@@ -0,0 +1,183 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// HTTPMethodAny can be used as the method of an HTTPRoute to match any HTTP method.
+const HTTPMethodAny = "ANY"
+
+// ErrHTTPNoRoute is wrapped by the 404 Not Found and 405 Method Not Allowed errors returned by HTTPRouter.
+var ErrHTTPNoRoute = errors.New("no http route")
+
+// HTTPMiddleware wraps the handler of an HTTPRoute, permitting guards or other behaviour to be added to individual
+// routes. Filter and Match can be used to construct middleware, see HTTPGuard.
+type HTTPMiddleware func(func(context.Context, []byte) ([]byte, error)) func(context.Context, []byte) ([]byte, error)
+
+// HTTPRoute is a single route used by HTTPRouter, it should be constructed with Route.
+type HTTPRoute struct {
+	method   string
+	segments []string
+	handler  func(context.Context, []byte) ([]byte, error)
+}
+
+// Route constructs an HTTPRoute for use with HTTPRouter. The path is a template, segments within braces are path
+// parameters (e.g. /orders/{id}), a final segment ending with a plus is greedy and will match the remainder of the path
+// (e.g. /files/{proxy+}). The middleware is applied to n in order, so the first middleware is the outermost.
+func Route(method string, path string, n func(context.Context, []byte) ([]byte, error), middleware ...HTTPMiddleware) HTTPRoute {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		n = middleware[i](n)
+	}
+
+	return HTTPRoute{
+		method:   strings.ToUpper(method),
+		segments: httpPathSegments(path),
+		handler:  n,
+	}
+}
+
+// HTTPRouter provides a function to select a route based upon the method and path of the HTTPRequest on the context, it
+// can be chained from any of the HTTP wraps. It fills a similar role to Switch, however it matches path templates and
+// extracts path parameters, which are added to the HTTPRequest and can be extracted with HTTPPathParameterFromContext.
+//
+// The path is split into segments before it is URL decoded, so an escaped slash (%2F) forms part of a path parameter,
+// except with APIGatewayProxy where API Gateway only provides the decoded path.
+//
+// Routes are evaluated in the order provided, the first matching route is used. If no route matches the path an
+// HTTPError of 404 Not Found is returned, if a route matches the path but not the method an HTTPError of 405 Method
+// Not Allowed is returned, with the Allow header of the response set to the methods that would have matched.
+//
+// Example:
+//
+//   APIGatewayProxy(HTTPRouter(
+//     Route(http.MethodGet, "/orders/{id}", HTTPDomainObject(getOrder, codec.JSON)),
+//     Route(http.MethodPost, "/orders", HTTPDomainObject(createOrder, codec.JSON), HTTPGuard(isAdmin, NewHTTPError(http.StatusForbidden, nil))),
+//   ))
+func HTTPRouter(routes ...HTTPRoute) func(context.Context, []byte) ([]byte, error) {
+	return func(ctx context.Context, d []byte) ([]byte, error) {
+		req, _ := HTTPRequestFromContext(ctx)
+		path := httpRequestSegments(req)
+		method := strings.ToUpper(req.Method)
+
+		var allowed []string
+
+		for _, r := range routes {
+			params, ok := httpPathMatch(r.segments, path)
+			if !ok {
+				continue
+			}
+
+			if r.method != HTTPMethodAny && r.method != method {
+				allowed = append(allowed, r.method)
+				continue
+			}
+
+			req.PathParameters = params
+			ctx = context.WithValue(ctx, contextKeyHTTPRequest, req)
+
+			return r.handler(ctx, d)
+		}
+
+		if len(allowed) == 0 {
+			return nil, NewHTTPError(http.StatusNotFound, ErrHTTPNoRoute)
+		}
+
+		if resp, ok := HTTPResponseFromContext(ctx); ok {
+			sort.Strings(allowed)
+			resp.Headers.Set("Allow", strings.Join(httpUniqueStrings(allowed), ", "))
+		}
+
+		return nil, NewHTTPError(http.StatusMethodNotAllowed, ErrHTTPNoRoute)
+	}
+}
+
+// HTTPGuard constructs HTTPMiddleware using Match, the route handler is only called if f returns true, otherwise err is
+// returned. Typically err would be an HTTPError, such as NewHTTPError(http.StatusUnauthorized, nil).
+func HTTPGuard(f func(context.Context, []byte) (bool, error), err error) HTTPMiddleware {
+	return func(n func(context.Context, []byte) ([]byte, error)) func(context.Context, []byte) ([]byte, error) {
+		return Match[[]byte](f, n, Err[[]byte](err))
+	}
+}
+
+// HTTPPathParameterFromContext retrieves a named path parameter from the HTTPRequest on the context, for use after an
+// HTTPRouter has selected a route, or with path parameters provided by API Gateway.
+func HTTPPathParameterFromContext(ctx context.Context, name string) (string, bool) {
+	if req, ok := HTTPRequestFromContext(ctx); ok {
+		v, ok := req.PathParameters[name]
+		return v, ok
+	} else {
+		return "", false
+	}
+}
+
+// httpPathSegments splits a path into its segments, ignoring any leading or trailing slash.
+func httpPathSegments(path string) []string {
+	path = strings.Trim(path, "/")
+
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+// httpRequestSegments splits the path of a request into URL decoded segments. The raw path is split before decoding
+// when available, so an escaped slash is retained within its segment rather than separating two segments.
+func httpRequestSegments(req HTTPRequest) []string {
+	if req.RawPath == "" {
+		return httpPathSegments(req.Path)
+	}
+
+	segments := httpPathSegments(req.RawPath)
+
+	for i, s := range segments {
+		segments[i] = httpPathUnescape(s)
+	}
+
+	return segments
+}
+
+// httpPathMatch matches path segments against a template, returning the extracted path parameters.
+func httpPathMatch(template []string, path []string) (map[string]string, bool) {
+	params := map[string]string{}
+
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "+}") && i == len(template)-1 {
+			if i >= len(path) {
+				return nil, false
+			}
+
+			params[t[1:len(t)-2]] = strings.Join(path[i:], "/")
+			return params, true
+		}
+
+		if i >= len(path) {
+			return nil, false
+		}
+
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			params[t[1:len(t)-1]] = path[i]
+		} else if t != path[i] {
+			return nil, false
+		}
+	}
+
+	return params, len(template) == len(path)
+}
+
+// httpUniqueStrings removes adjacent duplicates from a sorted slice.
+func httpUniqueStrings(s []string) []string {
+	var ret []string
+
+	for i, v := range s {
+		if i == 0 || s[i-1] != v {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,161 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHTTPRouter(t *testing.T) {
+	respond := func(s string) func(context.Context, []byte) ([]byte, error) {
+		return func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte(s), nil
+		}
+	}
+
+	router := HTTPRouter(
+		Route(http.MethodGet, "/orders", respond("list")),
+		Route(http.MethodPost, "/orders", respond("create")),
+		Route(http.MethodGet, "/orders/{id}", func(ctx context.Context, _ []byte) ([]byte, error) {
+			id, ok := HTTPPathParameterFromContext(ctx, "id")
+			assert.True(t, ok)
+			return []byte("get " + id), nil
+		}),
+		Route(HTTPMethodAny, "/files/{proxy+}", func(ctx context.Context, _ []byte) ([]byte, error) {
+			proxy, ok := HTTPPathParameterFromContext(ctx, "proxy")
+			assert.True(t, ok)
+			return []byte("file " + proxy), nil
+		}),
+	)
+
+	call := func(method string, path string) events.APIGatewayProxyResponse {
+		resp, err := APIGatewayProxy(router)(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: method, Path: path})
+		assert.NoError(t, err)
+		return resp
+	}
+
+	t.Run("the route matching the method and path is called", func(t *testing.T) {
+		assert.Equal(t, "list", call(http.MethodGet, "/orders").Body)
+		assert.Equal(t, "create", call(http.MethodPost, "/orders/").Body)
+	})
+
+	t.Run("path parameters are extracted and available on the context", func(t *testing.T) {
+		assert.Equal(t, "get 123", call(http.MethodGet, "/orders/123").Body)
+	})
+
+	t.Run("greedy path parameters match the remainder of the path", func(t *testing.T) {
+		assert.Equal(t, "file a/b/c.txt", call(http.MethodDelete, "/files/a/b/c.txt").Body)
+	})
+
+	t.Run("a greedy path parameter requires at least one segment", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/files").StatusCode)
+	})
+
+	t.Run("an unknown path results in a 404 Not Found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/customers").StatusCode)
+		assert.Equal(t, http.StatusNotFound, call(http.MethodGet, "/orders/123/items").StatusCode)
+	})
+
+	t.Run("a known path with an unknown method results in a 405 Method Not Allowed with an Allow header", func(t *testing.T) {
+		resp := call(http.MethodDelete, "/orders")
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+		assert.Equal(t, []string{"GET, POST"}, resp.MultiValueHeaders["Allow"])
+	})
+
+	t.Run("errors returned when no route matches wrap ErrHTTPNoRoute", func(t *testing.T) {
+		_, err := router(context.WithValue(context.TODO(), contextKeyHTTPRequest, HTTPRequest{Method: http.MethodGet, Path: "/unknown"}), nil)
+		assert.True(t, errors.Is(err, ErrHTTPNoRoute))
+	})
+}
+
+func TestHTTPRouter_Sources(t *testing.T) {
+	router := HTTPRouter(
+		Route(http.MethodGet, "/files/{name}", func(ctx context.Context, _ []byte) ([]byte, error) {
+			name, _ := HTTPPathParameterFromContext(ctx, "name")
+			return []byte(name), nil
+		}),
+	)
+
+	t.Run("the same route table extracts the same parameters from every source", func(t *testing.T) {
+		rest, err := APIGatewayProxy(router)(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/files/my file"})
+		assert.NoError(t, err)
+		assert.Equal(t, "my file", rest.Body)
+
+		v2 := events.APIGatewayV2HTTPRequest{RawPath: "/files/my%20file"}
+		v2.RequestContext.HTTP.Method = http.MethodGet
+		v2.RequestContext.Stage = "$default"
+
+		resp, err := APIGatewayV2HTTP(router)(context.TODO(), v2)
+		assert.NoError(t, err)
+		assert.Equal(t, "my file", resp.Body)
+
+		v2.RawPath = "/prod/files/my%20file"
+		v2.RequestContext.Stage = "prod"
+
+		resp, err = APIGatewayV2HTTP(router)(context.TODO(), v2)
+		assert.NoError(t, err)
+		assert.Equal(t, "my file", resp.Body)
+
+		furl := events.LambdaFunctionURLRequest{RawPath: "/files/my%20file"}
+		furl.RequestContext.HTTP.Method = http.MethodGet
+
+		urlResp, err := FunctionURL(router)(context.TODO(), furl)
+		assert.NoError(t, err)
+		assert.Equal(t, "my file", urlResp.Body)
+
+		alb, err := ALB(router)(context.TODO(), events.ALBTargetGroupRequest{HTTPMethod: http.MethodGet, Path: "/files/my%20file"})
+		assert.NoError(t, err)
+		assert.Equal(t, "my file", alb.Body)
+	})
+
+	t.Run("an escaped slash is part of a path parameter rather than separating segments", func(t *testing.T) {
+		v2 := events.APIGatewayV2HTTPRequest{RawPath: "/prod/files/a%2Fb"}
+		v2.RequestContext.HTTP.Method = http.MethodGet
+		v2.RequestContext.Stage = "prod"
+
+		resp, err := APIGatewayV2HTTP(router)(context.TODO(), v2)
+		assert.NoError(t, err)
+		assert.Equal(t, "a/b", resp.Body)
+
+		furl := events.LambdaFunctionURLRequest{RawPath: "/files/a%2Fb"}
+		furl.RequestContext.HTTP.Method = http.MethodGet
+
+		urlResp, err := FunctionURL(router)(context.TODO(), furl)
+		assert.NoError(t, err)
+		assert.Equal(t, "a/b", urlResp.Body)
+
+		alb, err := ALB(router)(context.TODO(), events.ALBTargetGroupRequest{HTTPMethod: http.MethodGet, Path: "/files/a%2Fb"})
+		assert.NoError(t, err)
+		assert.Equal(t, "a/b", alb.Body)
+
+		alb, err = ALB(router)(context.TODO(), events.ALBTargetGroupRequest{HTTPMethod: http.MethodGet, Path: "/files/a/b"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, alb.StatusCode)
+	})
+}
+
+func TestHTTPGuard(t *testing.T) {
+	guarded := func(allow bool) func(context.Context, []byte) ([]byte, error) {
+		return HTTPRouter(Route(http.MethodGet, "/", func(_ context.Context, _ []byte) ([]byte, error) {
+			return []byte("ok"), nil
+		}, HTTPGuard(func(_ context.Context, _ []byte) (bool, error) {
+			return allow, nil
+		}, NewHTTPError(http.StatusUnauthorized, nil))))
+	}
+
+	t.Run("the route handler is called if the guard matches", func(t *testing.T) {
+		resp, err := APIGatewayProxy(guarded(true))(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "ok", resp.Body)
+	})
+
+	t.Run("the guard error is returned if the guard does not match", func(t *testing.T) {
+		resp, err := APIGatewayProxy(guarded(false))(context.TODO(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
@@ -0,0 +1,171 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pwood/lambdawrap/codec"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestNewHTTPError(t *testing.T) {
+	t.Run("the status code is provided and the wrapped error is available", func(t *testing.T) {
+		err := NewHTTPError(http.StatusConflict, io.ErrUnexpectedEOF)
+
+		var he HTTPError
+		assert.True(t, errors.As(err, &he))
+		assert.Equal(t, http.StatusConflict, he.HTTPStatusCode())
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.Equal(t, io.ErrUnexpectedEOF.Error(), err.Error())
+	})
+
+	t.Run("a nil error uses the status text as the message", func(t *testing.T) {
+		err := NewHTTPError(http.StatusNotFound, nil)
+		assert.Equal(t, "Not Found", err.Error())
+	})
+}
+
+func TestHTTPDomainObject(t *testing.T) {
+	type in struct {
+		In string
+	}
+
+	type out struct {
+		Out string
+	}
+
+	httpCtx := func() (context.Context, *HTTPResponse) {
+		resp := &HTTPResponse{StatusCode: http.StatusOK, Headers: http.Header{}}
+		return context.WithValue(context.TODO(), contextKeyHTTPResponse, resp), resp
+	}
+
+	t.Run("an error during unmarshal is returned as a 400 Bad Request", func(t *testing.T) {
+		next := func(_ context.Context, i in) (out, error) {
+			t.Fatal("next called unexpectedly")
+			return out{}, nil
+		}
+
+		_, err := HTTPDomainObject(next, codec.JSON)(context.TODO(), []byte(`{"In":"message"`))
+
+		var he HTTPError
+		assert.True(t, errors.As(err, &he))
+		assert.Equal(t, http.StatusBadRequest, he.HTTPStatusCode())
+	})
+
+	t.Run("an empty body results in the zero value being provided to next", func(t *testing.T) {
+		next := func(_ context.Context, i in) (out, error) {
+			assert.Equal(t, in{}, i)
+			return out{Out: "empty"}, nil
+		}
+
+		d, err := HTTPDomainObject(next, codec.JSON)(context.TODO(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`{"Out":"empty"}`), d)
+	})
+
+	t.Run("an error from next is propagated", func(t *testing.T) {
+		next := func(_ context.Context, i in) (out, error) {
+			return out{}, NewHTTPError(http.StatusConflict, io.ErrUnexpectedEOF)
+		}
+
+		_, err := HTTPDomainObject(next, codec.JSON)(context.TODO(), []byte(`{"In":"message"}`))
+
+		var he HTTPError
+		assert.True(t, errors.As(err, &he))
+		assert.Equal(t, http.StatusConflict, he.HTTPStatusCode())
+	})
+
+	t.Run("the input is unmarshalled, next called, the output marshalled and the Content-Type set from the codec", func(t *testing.T) {
+		next := func(_ context.Context, i in) (out, error) {
+			return out{Out: i.In}, nil
+		}
+
+		ctx, resp := httpCtx()
+
+		d, err := HTTPDomainObject(next, codec.JSON)(ctx, []byte(`{"In":"message"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(`{"Out":"message"}`), d)
+		assert.Equal(t, "application/json", resp.Headers.Get("Content-Type"))
+	})
+
+	t.Run("a Content-Type set by next is not replaced", func(t *testing.T) {
+		next := func(ctx context.Context, i in) (out, error) {
+			resp, ok := HTTPResponseFromContext(ctx)
+			assert.True(t, ok)
+			resp.Headers.Set("Content-Type", "application/vnd.custom+json")
+
+			return out{Out: i.In}, nil
+		}
+
+		ctx, resp := httpCtx()
+
+		_, err := HTTPDomainObject(next, codec.JSON)(ctx, []byte(`{"In":"message"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "application/vnd.custom+json", resp.Headers.Get("Content-Type"))
+	})
+}
+
+func TestWithHTTPErrorHook(t *testing.T) {
+	t.Run("the original error from next is provided with the response status code", func(t *testing.T) {
+		expectedErr := errors.New("order 123 locked by user 456")
+
+		var (
+			hookErr    error
+			statusCode int
+			path       string
+		)
+
+		hook := WithHTTPErrorHook(func(ctx context.Context, err error) {
+			hookErr = err
+
+			resp, _ := HTTPResponseFromContext(ctx)
+			statusCode = resp.StatusCode
+
+			req, _ := HTTPRequestFromContext(ctx)
+			path = req.Path
+		})
+
+		next := Err[[]byte](NewHTTPError(http.StatusConflict, fmt.Errorf("lock: %w", expectedErr)))
+
+		resp, err := APIGatewayProxy(next, hook)(context.TODO(), events.APIGatewayProxyRequest{Path: "/orders/123"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Conflict", resp.Body)
+		assert.ErrorIs(t, hookErr, expectedErr)
+		assert.Equal(t, http.StatusConflict, statusCode)
+		assert.Equal(t, "/orders/123", path)
+	})
+
+	t.Run("the hook is called by every HTTP wrap", func(t *testing.T) {
+		var calls int
+
+		hook := WithHTTPErrorHook(func(_ context.Context, err error) {
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			calls++
+		})
+
+		next := Err[[]byte](io.ErrUnexpectedEOF)
+
+		_, _ = APIGatewayProxy(next, hook)(context.TODO(), events.APIGatewayProxyRequest{})
+		_, _ = APIGatewayV2HTTP(next, hook)(context.TODO(), events.APIGatewayV2HTTPRequest{})
+		_, _ = FunctionURL(next, hook)(context.TODO(), events.LambdaFunctionURLRequest{})
+		_, _ = ALB(next, hook)(context.TODO(), events.ALBTargetGroupRequest{})
+
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("the hook is not called when next succeeds", func(t *testing.T) {
+		called := false
+
+		hook := WithHTTPErrorHook(func(_ context.Context, _ error) {
+			called = true
+		})
+
+		_, err := APIGatewayProxy(Nop[[]byte](), hook)(context.TODO(), events.APIGatewayProxyRequest{})
+		assert.NoError(t, err)
+		assert.False(t, called)
+	})
+}
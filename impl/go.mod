@@ -3,7 +3,7 @@ module github.com/pwood/lambdawrap/impl
 go 1.18
 
 require (
-	github.com/aws/aws-lambda-go v1.28.0
+	github.com/aws/aws-lambda-go v1.38.0
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.24.1
 )
 
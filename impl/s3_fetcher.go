@@ -6,6 +6,8 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"io"
+	"net/http"
+	"strconv"
 )
 
 // S3 is a structure to provide an S3Fetcher used by the S3Fetch wrap in lambdawrap. It is a very simplistic
@@ -22,11 +24,64 @@ type S3 struct {
 }
 
 // Fetch is to be passed into the S3Fetch wrap, it will fetch the exact version of the object located in the bucket
-// from the S3Entity.
+// from the S3Entity. The URL decoded key is used as populated by S3Fetch, falling back to the key if it is absent.
+//
+// The io.ReadCloser returned is an *Object, which provides the metadata of the object to the S3FetchWithMetadata wrap
+// when adapted with S3HeaderMetadataFetcher.
+//
+//   S3FetchWithMetadata(next, S3HeaderMetadataFetcher(s3.Fetch))
 func (s *S3) Fetch(ctx context.Context, e events.S3Entity) (io.ReadCloser, error) {
+	out, err := s.getObject(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	h := http.Header{}
+
+	h.Set("Content-Length", strconv.FormatInt(out.ContentLength, 10))
+
+	for k, v := range map[string]*string{
+		"Content-Encoding": out.ContentEncoding,
+		"Content-Type":     out.ContentType,
+		"ETag":             out.ETag,
+		"X-Amz-Version-Id": out.VersionId,
+	} {
+		if v != nil {
+			h.Set(k, *v)
+		}
+	}
+
+	if out.LastModified != nil {
+		h.Set("Last-Modified", out.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	for k, v := range out.Metadata {
+		h.Set("X-Amz-Meta-"+k, v)
+	}
+
+	return &Object{ReadCloser: out.Body, header: h}, nil
+}
+
+// Object is the body of an S3 object returned by Fetch, along with its HTTP headers.
+type Object struct {
+	io.ReadCloser
+	header http.Header
+}
+
+// Header returns the HTTP headers of the object, satisfying S3ObjectHeaderer in lambdawrap.
+func (o *Object) Header() http.Header {
+	return o.header
+}
+
+func (s *S3) getObject(ctx context.Context, e events.S3Entity) (*s3.GetObjectOutput, error) {
+	key := e.Object.URLDecodedKey
+	if key == "" {
+		key = e.Object.Key
+	}
+
 	req := &s3.GetObjectInput{
 		Bucket:    &e.Bucket.Name,
-		Key:       &e.Object.Key,
+		Key:       &key,
 		VersionId: &e.Object.VersionID,
 	}
 
@@ -35,5 +90,5 @@ func (s *S3) Fetch(ctx context.Context, e events.S3Entity) (io.ReadCloser, error
 		return nil, fmt.Errorf("s3 fetch error: %w", err)
 	}
 
-	return out.Body, nil
+	return out, nil
 }
@@ -0,0 +1,112 @@
+package lambdawrap
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Kinesis provides a wrapper to iterate through multiple Kinesis records included in an events.KinesisEvent. Default
+// behaviour is to concatenate the []byte output from each record, returning to the caller.
+//
+// The base64 encoded data of each record is decoded by events.KinesisRecord, and then provided to next as a []byte,
+// string or unmarshalled with JSON in the same manner as SQS. It is recommended you use DomainObject instead.
+//
+// The partition key, sequence number and stream ARN of the record are added to the context, and can be extracted with
+// KinesisPartitionKeyFromContext, KinesisSequenceNumberFromContext and KinesisStreamARNFromContext.
+//
+// Records can be processed concurrently with WithConcurrency, and WithOrdered ensures that records sharing a partition
+// key are processed in order.
+func Kinesis[O any](n func(context.Context, O) ([]byte, error), opts ...BatchOption) func(context.Context, events.KinesisEvent) ([]byte, error) {
+	o := newBatchOptions(opts)
+
+	return func(ctx context.Context, e events.KinesisEvent) ([]byte, error) {
+		data, errs := batchRun(ctx, o, true, len(e.Records), kinesisOrderingKey(e.Records), func(ctx context.Context, i int) ([]byte, error) {
+			return kinesisRecord(ctx, e.Records[i], n)
+		})
+
+		return batchConcat(o, data, errs)
+	}
+}
+
+// KinesisBatchItemFailures provides a wrapper to iterate through multiple Kinesis records included in an
+// events.KinesisEvent, reporting the first record that fails as an events.KinesisEventResponse. Processing stops at the
+// first record that fails to unmarshal or returns an error from next, its sequence number is returned as the only
+// BatchItemFailures entry. Lambda treats this as a checkpoint, records before it are considered committed, the failing
+// record and all those after it will be retried. The Lambda event source mapping must have ReportBatchItemFailures
+// enabled, otherwise the response is ignored.
+//
+// The []byte output of next is discarded, use Output if the output of each record is needed.
+//
+// Records can be processed concurrently with WithConcurrency, in which case the checkpoint is the earliest record that
+// failed or was skipped. It is recommended that WithOrdered is also used, so records sharing a partition key are not
+// processed out of order.
+func KinesisBatchItemFailures[O any](n func(context.Context, O) ([]byte, error), opts ...BatchOption) func(context.Context, events.KinesisEvent) (events.KinesisEventResponse, error) {
+	o := newBatchOptions(opts)
+
+	return func(ctx context.Context, e events.KinesisEvent) (events.KinesisEventResponse, error) {
+		ret := events.KinesisEventResponse{BatchItemFailures: []events.KinesisBatchItemFailure{}}
+
+		_, errs := batchRun(ctx, o, true, len(e.Records), kinesisOrderingKey(e.Records), func(ctx context.Context, i int) ([]byte, error) {
+			return kinesisRecord(ctx, e.Records[i], n)
+		})
+
+		if i := batchCheckpoint(errs); i >= 0 {
+			ret.BatchItemFailures = append(ret.BatchItemFailures, events.KinesisBatchItemFailure{ItemIdentifier: e.Records[i].Kinesis.SequenceNumber})
+		}
+
+		return ret, nil
+	}
+}
+
+func kinesisOrderingKey(r []events.KinesisEventRecord) func(int) string {
+	return func(i int) string {
+		return r[i].EventSourceArn + "/" + r[i].Kinesis.PartitionKey
+	}
+}
+
+func kinesisRecord[O any](ctx context.Context, r events.KinesisEventRecord, n func(context.Context, O) ([]byte, error)) ([]byte, error) {
+	ctx = context.WithValue(ctx, contextKeyKinesisPartitionKey, r.Kinesis.PartitionKey)
+	ctx = context.WithValue(ctx, contextKeyKinesisSequenceNumber, r.Kinesis.SequenceNumber)
+	ctx = context.WithValue(ctx, contextKeyKinesisStreamARN, r.EventSourceArn)
+
+	if p, err := sliceStringOrUnmarshal[O](r.Kinesis.Data); err != nil {
+		return nil, fmt.Errorf("Kinesis unmarshal: %w", err)
+	} else {
+		if d, err := n(ctx, p); err != nil {
+			return nil, fmt.Errorf("Kinesis next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
+
+// KinesisPartitionKeyFromContext retrieves a Kinesis partition key from the context, for use after a Kinesis wrap has
+// been used if the application needs the partition key of the record.
+func KinesisPartitionKeyFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyKinesisPartitionKey); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+// KinesisSequenceNumberFromContext retrieves a Kinesis sequence number from the context, for use after a Kinesis wrap
+// has been used if the application needs the sequence number of the record.
+func KinesisSequenceNumberFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyKinesisSequenceNumber); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+// KinesisStreamARNFromContext retrieves a Kinesis stream ARN from the context, for use after a Kinesis wrap has been
+// used if the application needs the stream that the record was provided on.
+func KinesisStreamARNFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyKinesisStreamARN); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
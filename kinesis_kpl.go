@@ -0,0 +1,227 @@
+package lambdawrap
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+)
+
+var kplMagic = []byte{0xf3, 0x89, 0x9a, 0xc2}
+
+// ErrKPLMalformed is returned if a Kinesis Producer Library aggregated record can not be decoded.
+var ErrKPLMalformed = errors.New("malformed kpl aggregated record")
+
+// KinesisDeaggregate consumes the data from a Kinesis record, and if it is a Kinesis Producer Library (KPL) aggregated
+// record, calls next for each user record contained within. Records which are not aggregated are passed to next
+// unaltered. Default behaviour is to concatenate the []byte output from each user record, returning to the caller.
+//
+// An aggregated record is identified by the KPL magic prefix, and is only treated as aggregated if the trailing MD5
+// digest matches the protobuf message, this mirrors the behaviour of the KPL and KCL.
+//
+// The partition key and explicit hash key of the user record are added to the context, and can be extracted with
+// KinesisPartitionKeyFromContext and KinesisExplicitHashKeyFromContext.
+//
+// KinesisDeaggregate will attempt to unmarshal any destination structure with JSON in the same way as Kinesis. It is
+// recommended you use DomainObject instead.
+//
+// Example:
+//
+//   Kinesis(KinesisDeaggregate(DomainObject(myFunc, codec.JSON)))
+func KinesisDeaggregate[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, []byte) ([]byte, error) {
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		agg, ok, err := kplDecode(data)
+		if err != nil {
+			return nil, fmt.Errorf("KinesisDeaggregate decode: %w", err)
+		}
+
+		if !ok {
+			if p, err := sliceStringOrUnmarshal[O](data); err != nil {
+				return nil, fmt.Errorf("KinesisDeaggregate unmarshal: %w", err)
+			} else if d, err := n(ctx, p); err != nil {
+				return nil, fmt.Errorf("KinesisDeaggregate next: %w", err)
+			} else {
+				return d, nil
+			}
+		}
+
+		var ret []byte
+
+		for i, r := range agg.records {
+			rCtx := ctx
+
+			if r.partitionKeyIndex >= uint64(len(agg.partitionKeys)) {
+				return nil, fmt.Errorf("KinesisDeaggregate record %d: partition key index out of range: %w", i, ErrKPLMalformed)
+			}
+
+			rCtx = context.WithValue(rCtx, contextKeyKinesisPartitionKey, agg.partitionKeys[r.partitionKeyIndex])
+
+			if r.hasExplicitHashKey {
+				if r.explicitHashKeyIndex >= uint64(len(agg.explicitHashKeys)) {
+					return nil, fmt.Errorf("KinesisDeaggregate record %d: explicit hash key index out of range: %w", i, ErrKPLMalformed)
+				}
+
+				rCtx = context.WithValue(rCtx, contextKeyKinesisExplicitHashKey, agg.explicitHashKeys[r.explicitHashKeyIndex])
+			}
+
+			if p, err := sliceStringOrUnmarshal[O](r.data); err != nil {
+				return nil, fmt.Errorf("KinesisDeaggregate record %d unmarshal: %w", i, err)
+			} else if d, err := n(rCtx, p); err != nil {
+				return nil, fmt.Errorf("KinesisDeaggregate record %d next: %w", i, err)
+			} else {
+				ret = append(ret, d...)
+			}
+		}
+
+		return ret, nil
+	}
+}
+
+// KinesisExplicitHashKeyFromContext retrieves the explicit hash key of a KPL user record from the context, for use
+// after a KinesisDeaggregate wrap has been used. It is only present if the user record was aggregated and the producer
+// provided an explicit hash key.
+func KinesisExplicitHashKeyFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(contextKeyKinesisExplicitHashKey); val != nil {
+		return val.(string), true
+	} else {
+		return "", false
+	}
+}
+
+type kplAggregatedRecord struct {
+	partitionKeys    []string
+	explicitHashKeys []string
+	records          []kplRecord
+}
+
+type kplRecord struct {
+	partitionKeyIndex    uint64
+	explicitHashKeyIndex uint64
+	hasExplicitHashKey   bool
+	data                 []byte
+}
+
+// kplDecode decodes a KPL aggregated record, returning false if data is not an aggregated record.
+func kplDecode(data []byte) (kplAggregatedRecord, bool, error) {
+	if len(data) < len(kplMagic)+md5.Size || !bytes.HasPrefix(data, kplMagic) {
+		return kplAggregatedRecord{}, false, nil
+	}
+
+	message := data[len(kplMagic) : len(data)-md5.Size]
+	digest := md5.Sum(message)
+
+	if !bytes.Equal(digest[:], data[len(data)-md5.Size:]) {
+		return kplAggregatedRecord{}, false, nil
+	}
+
+	var agg kplAggregatedRecord
+
+	err := protobufFields(message, func(field uint64, value []byte, _ uint64) error {
+		switch field {
+		case 1:
+			agg.partitionKeys = append(agg.partitionKeys, string(value))
+		case 2:
+			agg.explicitHashKeys = append(agg.explicitHashKeys, string(value))
+		case 3:
+			r, err := kplDecodeRecord(value)
+			if err != nil {
+				return err
+			}
+
+			agg.records = append(agg.records, r)
+		}
+
+		return nil
+	})
+
+	return agg, true, err
+}
+
+func kplDecodeRecord(data []byte) (kplRecord, error) {
+	var r kplRecord
+
+	err := protobufFields(data, func(field uint64, value []byte, varint uint64) error {
+		switch field {
+		case 1:
+			r.partitionKeyIndex = varint
+		case 2:
+			r.explicitHashKeyIndex = varint
+			r.hasExplicitHashKey = true
+		case 3:
+			r.data = value
+		}
+
+		return nil
+	})
+
+	return r, err
+}
+
+// protobufFields walks the fields of a protobuf message, calling fn with the field number and either the value of a
+// length delimited field, or the value of a varint field. Fixed width fields are skipped.
+func protobufFields(data []byte, fn func(uint64, []byte, uint64) error) error {
+	for len(data) > 0 {
+		key, n := protobufVarint(data)
+		if n == 0 {
+			return fmt.Errorf("invalid field key: %w", ErrKPLMalformed)
+		}
+		data = data[n:]
+
+		field, wireType := key>>3, key&0x7
+
+		switch wireType {
+		case 0:
+			v, n := protobufVarint(data)
+			if n == 0 {
+				return fmt.Errorf("invalid varint in field %d: %w", field, ErrKPLMalformed)
+			}
+			data = data[n:]
+
+			if err := fn(field, nil, v); err != nil {
+				return err
+			}
+		case 1:
+			if len(data) < 8 {
+				return fmt.Errorf("truncated fixed64 in field %d: %w", field, ErrKPLMalformed)
+			}
+			data = data[8:]
+		case 2:
+			l, n := protobufVarint(data)
+			if n == 0 || l > uint64(len(data)-n) {
+				return fmt.Errorf("invalid length in field %d: %w", field, ErrKPLMalformed)
+			}
+			data = data[n:]
+
+			if err := fn(field, data[:l], 0); err != nil {
+				return err
+			}
+			data = data[l:]
+		case 5:
+			if len(data) < 4 {
+				return fmt.Errorf("truncated fixed32 in field %d: %w", field, ErrKPLMalformed)
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d in field %d: %w", wireType, field, ErrKPLMalformed)
+		}
+	}
+
+	return nil
+}
+
+// protobufVarint decodes a varint from the start of data, returning the value and number of bytes consumed. Zero bytes
+// consumed indicates an invalid or truncated varint.
+func protobufVarint(data []byte) (uint64, int) {
+	var v uint64
+
+	for i := 0; i < len(data) && i < 10; i++ {
+		v |= uint64(data[i]&0x7f) << (7 * i)
+
+		if data[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+
+	return 0, 0
+}
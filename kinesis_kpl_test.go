@@ -0,0 +1,139 @@
+package lambdawrap
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestKinesisDeaggregate(t *testing.T) {
+	pbVarint := func(v uint64) []byte {
+		var b []byte
+		for v >= 0x80 {
+			b = append(b, byte(v)|0x80)
+			v >>= 7
+		}
+		return append(b, byte(v))
+	}
+
+	pbBytes := func(field uint64, v []byte) []byte {
+		b := pbVarint(field<<3 | 2)
+		b = append(b, pbVarint(uint64(len(v)))...)
+		return append(b, v...)
+	}
+
+	pbUint := func(field uint64, v uint64) []byte {
+		return append(pbVarint(field<<3), pbVarint(v)...)
+	}
+
+	aggregate := func(message []byte) []byte {
+		digest := md5.Sum(message)
+
+		var b []byte
+		b = append(b, kplMagic...)
+		b = append(b, message...)
+		return append(b, digest[:]...)
+	}
+
+	var message []byte
+	message = append(message, pbBytes(1, []byte("pk-a"))...)
+	message = append(message, pbBytes(1, []byte("pk-b"))...)
+	message = append(message, pbBytes(2, []byte("1234"))...)
+
+	var first []byte
+	first = append(first, pbUint(1, 0)...)
+	first = append(first, pbBytes(3, []byte("{\"val\": \"1\"}"))...)
+	message = append(message, pbBytes(3, first)...)
+
+	var second []byte
+	second = append(second, pbUint(1, 1)...)
+	second = append(second, pbUint(2, 0)...)
+	second = append(second, pbBytes(3, []byte("{\"val\": \"2\"}"))...)
+	message = append(message, pbBytes(3, second)...)
+
+	type myStruct struct {
+		Val string
+	}
+
+	t.Run("records which are not aggregated are passed to next unaltered", func(t *testing.T) {
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return d, nil
+		}
+
+		d, err := KinesisDeaggregate(next)(context.TODO(), []byte("data"))
+		assert.NoError(t, err)
+		assert.Equal(t, "data", string(d))
+	})
+
+	t.Run("aggregated records call next for each user record with keys available on context, and the result is aggregated", func(t *testing.T) {
+		expected := map[string][2]string{
+			"1": {"pk-a", ""},
+			"2": {"pk-b", "1234"},
+		}
+
+		next := func(ctx context.Context, d myStruct) ([]byte, error) {
+			partitionKey, ok := KinesisPartitionKeyFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, expected[d.Val][0], partitionKey)
+
+			explicitHashKey, _ := KinesisExplicitHashKeyFromContext(ctx)
+			assert.Equal(t, expected[d.Val][1], explicitHashKey)
+
+			return []byte(d.Val), nil
+		}
+
+		d, err := KinesisDeaggregate(next)(context.TODO(), aggregate(message))
+		assert.NoError(t, err)
+		assert.Equal(t, "12", string(d))
+	})
+
+	t.Run("records with the magic prefix but a mismatched digest are treated as not aggregated", func(t *testing.T) {
+		data := aggregate(message)
+		data[len(data)-1] ^= 0xff
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return d, nil
+		}
+
+		d, err := KinesisDeaggregate(next)(context.TODO(), data)
+		assert.NoError(t, err)
+		assert.Equal(t, data, d)
+	})
+
+	t.Run("an aggregated record referencing a missing partition key results in an error", func(t *testing.T) {
+		record := append(pbUint(1, 5), pbBytes(3, []byte("1"))...)
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			t.Fatal("next called unexpectedly")
+			return nil, nil
+		}
+
+		d, err := KinesisDeaggregate(next)(context.TODO(), aggregate(pbBytes(3, record)))
+		assert.True(t, errors.Is(err, ErrKPLMalformed))
+		assert.Nil(t, d)
+	})
+
+	t.Run("a truncated aggregated record results in an error", func(t *testing.T) {
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			t.Fatal("next called unexpectedly")
+			return nil, nil
+		}
+
+		d, err := KinesisDeaggregate(next)(context.TODO(), aggregate(message[:len(message)-3]))
+		assert.True(t, errors.Is(err, ErrKPLMalformed))
+		assert.Nil(t, d)
+	})
+
+	t.Run("an error from next will result in an error", func(t *testing.T) {
+		next := func(_ context.Context, d myStruct) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		d, err := KinesisDeaggregate(next)(context.TODO(), aggregate(message))
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.Nil(t, d)
+	})
+}
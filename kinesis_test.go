@@ -0,0 +1,176 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestKinesis(t *testing.T) {
+	t.Run("each event.KinesisEventRecord is processed, calls the next function that takes a []byte and the result is aggregated", func(t *testing.T) {
+		in := events.KinesisEvent{
+			Records: []events.KinesisEventRecord{
+				{
+					Kinesis: events.KinesisRecord{Data: []byte("1")},
+				},
+				{
+					Kinesis: events.KinesisRecord{Data: []byte("2")},
+				},
+				{
+					Kinesis: events.KinesisRecord{Data: []byte("3")},
+				},
+			},
+		}
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return d, nil
+		}
+
+		d, err := Kinesis(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "123", string(d))
+	})
+
+	t.Run("each event.KinesisEventRecord is processed, calls the next function that takes a structure, the result is aggregated and record details are available on context", func(t *testing.T) {
+		type myStruct struct {
+			Val string
+		}
+
+		in := events.KinesisEvent{
+			Records: []events.KinesisEventRecord{
+				{
+					EventSourceArn: "streamARN",
+					Kinesis: events.KinesisRecord{
+						PartitionKey:   "pk",
+						SequenceNumber: "1",
+						Data:           []byte("{\"val\": \"1\"}"),
+					},
+				},
+				{
+					EventSourceArn: "streamARN",
+					Kinesis: events.KinesisRecord{
+						PartitionKey:   "pk",
+						SequenceNumber: "2",
+						Data:           []byte("{\"val\": \"2\"}"),
+					},
+				},
+			},
+		}
+
+		next := func(ctx context.Context, d myStruct) ([]byte, error) {
+			partitionKey, ok := KinesisPartitionKeyFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "pk", partitionKey)
+
+			sequenceNumber, ok := KinesisSequenceNumberFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, d.Val, sequenceNumber)
+
+			streamARN, ok := KinesisStreamARNFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "streamARN", streamARN)
+
+			return []byte(d.Val), nil
+		}
+
+		d, err := Kinesis(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "12", string(d))
+	})
+
+	t.Run("unmarshalling data with a JSON error will result in an error", func(t *testing.T) {
+		type myStruct struct {
+			Val string
+		}
+
+		in := events.KinesisEvent{
+			Records: []events.KinesisEventRecord{
+				{
+					Kinesis: events.KinesisRecord{Data: []byte("{\"val\": \"1\"")},
+				},
+			},
+		}
+
+		next := func(_ context.Context, d myStruct) ([]byte, error) {
+			return []byte(d.Val), nil
+		}
+
+		d, err := Kinesis(next)(context.TODO(), in)
+		assert.Error(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("an error from next will result in an error", func(t *testing.T) {
+		in := events.KinesisEvent{
+			Records: []events.KinesisEventRecord{
+				{
+					Kinesis: events.KinesisRecord{Data: []byte("1")},
+				},
+			},
+		}
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		d, err := Kinesis(next)(context.TODO(), in)
+		assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		assert.Nil(t, d)
+	})
+}
+
+func TestKinesisBatchItemFailures(t *testing.T) {
+	t.Run("processing stops at the first failing record, and its sequence number is reported as the checkpoint", func(t *testing.T) {
+		in := events.KinesisEvent{
+			Records: []events.KinesisEventRecord{
+				{
+					Kinesis: events.KinesisRecord{SequenceNumber: "100", Data: []byte("1")},
+				},
+				{
+					Kinesis: events.KinesisRecord{SequenceNumber: "200", Data: []byte("2")},
+				},
+				{
+					Kinesis: events.KinesisRecord{SequenceNumber: "300", Data: []byte("3")},
+				},
+			},
+		}
+
+		var seen []string
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			seen = append(seen, d)
+
+			if d == "2" {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, nil
+		}
+
+		resp, err := KinesisBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2"}, seen)
+		assert.Equal(t, []events.KinesisBatchItemFailure{{ItemIdentifier: "200"}}, resp.BatchItemFailures)
+	})
+
+	t.Run("no failures results in an empty set of batch item failures", func(t *testing.T) {
+		in := events.KinesisEvent{
+			Records: []events.KinesisEventRecord{
+				{
+					Kinesis: events.KinesisRecord{SequenceNumber: "100", Data: []byte("1")},
+				},
+			},
+		}
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return d, nil
+		}
+
+		resp, err := KinesisBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Empty(t, resp.BatchItemFailures)
+	})
+}
@@ -0,0 +1,150 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"reflect"
+	"sync/atomic"
+)
+
+// HandlerOption configures the lambda.Handler returned by Handler.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	before    []func(context.Context, []byte) (context.Context, error)
+	after     []func(context.Context, []byte, error) ([]byte, error)
+	errorType func(error) string
+}
+
+// WithBeforeInvoke adds a hook called with the raw payload before the chain, it may return a new context to pass to the
+// chain, e.g. to add a logger. An error from the hook is returned as the result of the invocation, and the chain is not
+// called. Hooks are called in the order they were added.
+func WithBeforeInvoke(f func(context.Context, []byte) (context.Context, error)) HandlerOption {
+	return func(o *handlerOptions) {
+		o.before = append(o.before, f)
+	}
+}
+
+// WithAfterInvoke adds a hook called with the output and error of the chain (or of a failing before hook), it may
+// replace both, e.g. to record metrics or to suppress an error. Hooks are called in the order they were added.
+func WithAfterInvoke(f func(context.Context, []byte, error) ([]byte, error)) HandlerOption {
+	return func(o *handlerOptions) {
+		o.after = append(o.after, f)
+	}
+}
+
+// WithErrorType sets the function used to provide the errorType reported to Lambda for an error, replacing the
+// default behaviour described by Handler.
+func WithErrorType(f func(error) string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.errorType = f
+	}
+}
+
+// LambdaErrorTyper can be implemented by errors returned from a chain, to control the errorType reported to Lambda.
+type LambdaErrorTyper interface {
+	// LambdaErrorType returns the errorType reported to Lambda, e.g. ValidationError.
+	LambdaErrorType() string
+}
+
+// Handler adapts a chain into a lambda.Handler, which can be passed to lambda.Start. Unlike passing the chain directly
+// to lambda.Start, the []byte output of the chain is returned to the caller verbatim, rather than being JSON encoded as
+// a base64 string. The payload is unmarshalled into E in the same way as SNS, so E may be an event such as
+// events.SQSEvent, or []byte or json.RawMessage to receive the raw payload.
+//
+// Errors are reported to Lambda with an errorMessage of the error, and an errorType provided by the first error in the
+// chain that implements LambdaErrorTyper, or otherwise the type name of the innermost wrapped error. This can be
+// changed with WithErrorType.
+//
+// The first invocation handled by each Handler is marked as a cold start, which can be extracted with
+// ColdStartFromContext.
+//
+// Example:
+//
+//   lambda.Start(Handler(SQS(DomainObject(SideEffect(myFunc), codec.JSON)), WithAfterInvoke(recordMetrics)))
+func Handler[E any](n func(context.Context, E) ([]byte, error), opts ...HandlerOption) lambda.Handler {
+	o := handlerOptions{errorType: lambdaErrorType}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &handler[E]{n: n, o: o}
+}
+
+// ColdStartFromContext retrieves whether the invocation is the first handled by the Handler, for use after a Handler
+// has been used.
+func ColdStartFromContext(ctx context.Context) (bool, bool) {
+	if val := ctx.Value(contextKeyColdStart); val != nil {
+		return val.(bool), true
+	} else {
+		return false, false
+	}
+}
+
+type handler[E any] struct {
+	n       func(context.Context, E) ([]byte, error)
+	o       handlerOptions
+	invoked int32
+}
+
+// Invoke implements lambda.Handler.
+func (h *handler[E]) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	ctx = context.WithValue(ctx, contextKeyColdStart, atomic.CompareAndSwapInt32(&h.invoked, 0, 1))
+
+	d, err := h.invoke(ctx, payload)
+
+	for _, f := range h.o.after {
+		d, err = f(ctx, d, err)
+	}
+
+	if err != nil {
+		var ire messages.InvokeResponse_Error
+		if errors.As(err, &ire) {
+			return nil, ire
+		}
+
+		return nil, messages.InvokeResponse_Error{Message: err.Error(), Type: h.o.errorType(err)}
+	}
+
+	return d, nil
+}
+
+func (h *handler[E]) invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	for _, f := range h.o.before {
+		var err error
+
+		if ctx, err = f(ctx, payload); err != nil {
+			return nil, fmt.Errorf("Handler before invoke: %w", err)
+		}
+	}
+
+	if e, err := sliceStringOrUnmarshal[E](payload); err != nil {
+		return nil, fmt.Errorf("Handler unmarshal: %w", err)
+	} else {
+		return h.n(ctx, e)
+	}
+}
+
+// lambdaErrorType provides the errorType of an error, from the first error implementing LambdaErrorTyper or the type
+// name of the innermost wrapped error.
+func lambdaErrorType(err error) string {
+	var et LambdaErrorTyper
+	if errors.As(err, &et) {
+		return et.LambdaErrorType()
+	}
+
+	for u := errors.Unwrap(err); u != nil; u = errors.Unwrap(u) {
+		err = u
+	}
+
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
@@ -0,0 +1,173 @@
+package lambdawrap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testTypedError struct{}
+
+func (testTypedError) Error() string {
+	return "typed"
+}
+
+func (testTypedError) LambdaErrorType() string {
+	return "ValidationError"
+}
+
+type testPointerError struct{}
+
+func (*testPointerError) Error() string {
+	return "pointer"
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("the output of the chain is returned verbatim", func(t *testing.T) {
+		next := func(_ context.Context, e events.SQSEvent) ([]byte, error) {
+			return []byte(`{"id":"` + e.Records[0].Body + `"}`), nil
+		}
+
+		d, err := Handler(next).Invoke(context.TODO(), []byte(`{"Records":[{"body":"1"}]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":"1"}`, string(d))
+	})
+
+	t.Run("the raw payload is provided to a chain taking []byte or json.RawMessage", func(t *testing.T) {
+		d, err := Handler(func(_ context.Context, d []byte) ([]byte, error) {
+			return d, nil
+		}).Invoke(context.TODO(), []byte(`{"a":1}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(d))
+
+		d, err = Handler(func(_ context.Context, d json.RawMessage) ([]byte, error) {
+			return d, nil
+		}).Invoke(context.TODO(), []byte(`{"a":1}`))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(d))
+	})
+
+	t.Run("the first invocation is marked as a cold start", func(t *testing.T) {
+		var starts []bool
+
+		h := Handler(func(ctx context.Context, _ []byte) ([]byte, error) {
+			cold, ok := ColdStartFromContext(ctx)
+			assert.True(t, ok)
+			starts = append(starts, cold)
+			return nil, nil
+		})
+
+		for i := 0; i < 3; i++ {
+			_, err := h.Invoke(context.TODO(), nil)
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, []bool{true, false, false}, starts)
+	})
+
+	t.Run("hooks are called around the chain in order", func(t *testing.T) {
+		var calls []string
+		type key struct{}
+
+		h := Handler(func(ctx context.Context, d []byte) ([]byte, error) {
+			calls = append(calls, "chain:"+ctx.Value(key{}).(string))
+			return d, nil
+		},
+			WithBeforeInvoke(func(ctx context.Context, payload []byte) (context.Context, error) {
+				calls = append(calls, "before1:"+string(payload))
+				return context.WithValue(ctx, key{}, "value"), nil
+			}),
+			WithBeforeInvoke(func(ctx context.Context, _ []byte) (context.Context, error) {
+				calls = append(calls, "before2")
+				return ctx, nil
+			}),
+			WithAfterInvoke(func(_ context.Context, d []byte, err error) ([]byte, error) {
+				calls = append(calls, "after1:"+string(d))
+				return append(d, '!'), err
+			}),
+			WithAfterInvoke(func(_ context.Context, d []byte, err error) ([]byte, error) {
+				calls = append(calls, "after2:"+string(d))
+				return d, err
+			}),
+		)
+
+		d, err := h.Invoke(context.TODO(), []byte("in"))
+		assert.NoError(t, err)
+		assert.Equal(t, "in!", string(d))
+		assert.Equal(t, []string{"before1:in", "before2", "chain:value", "after1:in", "after2:in!"}, calls)
+	})
+
+	t.Run("an error from a before hook skips the chain and is passed to after hooks", func(t *testing.T) {
+		expected := errors.New("expected")
+		called := false
+		var afterErr error
+
+		h := Handler(func(_ context.Context, _ []byte) ([]byte, error) {
+			called = true
+			return nil, nil
+		},
+			WithBeforeInvoke(func(ctx context.Context, _ []byte) (context.Context, error) {
+				return ctx, expected
+			}),
+			WithAfterInvoke(func(_ context.Context, d []byte, err error) ([]byte, error) {
+				afterErr = err
+				return d, err
+			}),
+		)
+
+		_, err := h.Invoke(context.TODO(), nil)
+		assert.Error(t, err)
+		assert.False(t, called)
+		assert.ErrorIs(t, afterErr, expected)
+	})
+
+	t.Run("an after hook can suppress an error", func(t *testing.T) {
+		h := Handler(Err[[]byte](errors.New("expected")), WithAfterInvoke(func(_ context.Context, _ []byte, _ error) ([]byte, error) {
+			return []byte("recovered"), nil
+		}))
+
+		d, err := h.Invoke(context.TODO(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "recovered", string(d))
+	})
+
+	t.Run("errors are mapped to a Lambda function error", func(t *testing.T) {
+		for expected, e := range map[string]error{
+			"errorString":      fmt.Errorf("SQS next: %w", errors.New("base")),
+			"ValidationError":  fmt.Errorf("SQS next: %w", testTypedError{}),
+			"testPointerError": fmt.Errorf("SQS next: %w", &testPointerError{}),
+		} {
+			_, err := Handler(Err[[]byte](e)).Invoke(context.TODO(), nil)
+
+			var ire messages.InvokeResponse_Error
+			assert.True(t, errors.As(err, &ire))
+			assert.Equal(t, expected, ire.Type)
+			assert.Equal(t, e.Error(), ire.Message)
+		}
+	})
+
+	t.Run("an InvokeResponse_Error is returned as is", func(t *testing.T) {
+		expected := messages.InvokeResponse_Error{Message: "message", Type: "Custom"}
+
+		_, err := Handler(Err[[]byte](fmt.Errorf("wrapped: %w", expected))).Invoke(context.TODO(), nil)
+		assert.Equal(t, expected, err)
+	})
+
+	t.Run("the error type can be customised", func(t *testing.T) {
+		_, err := Handler(Err[[]byte](errors.New("base")), WithErrorType(func(error) string {
+			return "Custom"
+		})).Invoke(context.TODO(), nil)
+
+		assert.Equal(t, messages.InvokeResponse_Error{Message: "base", Type: "Custom"}, err)
+	})
+
+	t.Run("payloads which can not be unmarshalled return an error", func(t *testing.T) {
+		_, err := Handler(Nop[events.SQSEvent]()).Invoke(context.TODO(), []byte("not json"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,429 @@
+// Package lambdatest provides an in-process emulation of the Lambda Runtime API, permitting chains to be tested end to
+// end with the aws-lambda-go runtime loop, as started by lambda.Start, without deploying them.
+package lambdatest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiPrefix = "/2018-06-01/runtime/"
+
+	headerRequestID   = "Lambda-Runtime-Aws-Request-Id"
+	headerDeadlineMS  = "Lambda-Runtime-Deadline-Ms"
+	headerFunctionARN = "Lambda-Runtime-Invoked-Function-Arn"
+	headerTraceID     = "Lambda-Runtime-Trace-Id"
+
+	envRuntimeAPI = "AWS_LAMBDA_RUNTIME_API"
+
+	// DefaultTimeout is the timeout of an invocation if WithTimeout is not used, matching the default of Lambda.
+	DefaultTimeout = 3 * time.Second
+	// DefaultFunctionARN is the invoked function ARN if WithFunctionARN is not used.
+	DefaultFunctionARN = "arn:aws:lambda:us-east-1:123456789012:function:lambdatest"
+)
+
+var (
+	// ErrClosed is returned when waiting for the result of an invocation, if the Runtime is closed first.
+	ErrClosed = errors.New("runtime closed")
+	// ErrUnknownRequest is returned when waiting for the result of a request ID which was not enqueued.
+	ErrUnknownRequest = errors.New("unknown request id")
+)
+
+// Option configures a Runtime.
+type Option func(*Runtime)
+
+// WithTimeout sets the timeout of each invocation, used to calculate the deadline provided to the function.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runtime) {
+		r.timeout = d
+	}
+}
+
+// WithFunctionARN sets the invoked function ARN provided to the function.
+func WithFunctionARN(arn string) Option {
+	return func(r *Runtime) {
+		r.functionARN = arn
+	}
+}
+
+// Result is the outcome of an invocation, as reported by the function to the Runtime API.
+type Result struct {
+	// RequestID is the request ID of the invocation.
+	RequestID string
+	// Deadline is the deadline provided to the function.
+	Deadline time.Time
+	// Payload is the response of the function, it is nil if the invocation failed.
+	Payload []byte
+	// ContentType is the content type of the response.
+	ContentType string
+	// Error is the error reported by the function, it is nil if the invocation succeeded.
+	Error *messages.InvokeResponse_Error
+}
+
+// Runtime is an in-process HTTP server implementing the Lambda Runtime API. Payloads are queued with Enqueue or Invoke,
+// and provided in order to the runtime loop polling for the next invocation, the response or error reported for each
+// is recorded as a Result.
+//
+// A Runtime should be started with Start, which runs lambda.StartWithOptions in the background. The runtime loop can
+// not be stopped, so after Close it is left waiting for an invocation that never arrives. As in Lambda, a panic in the
+// handler causes aws-lambda-go to exit the process, which will end the test binary.
+//
+// Example:
+//
+//   rt := lambdatest.NewRuntime()
+//   defer rt.Close()
+//
+//   rt.Start(lambdawrap.Handler(SQS(DomainObject(SideEffect(myFunc), codec.JSON))))
+//
+//   res, err := rt.Invoke(ctx, []byte(`{"Records":[{"body":"{}"}]}`))
+type Runtime struct {
+	listener    net.Listener
+	timeout     time.Duration
+	functionARN string
+
+	mu          sync.Mutex
+	queue       []*invocation
+	invocations map[string]*invocation
+	results     []Result
+	initError   *messages.InvokeResponse_Error
+	wake        chan struct{}
+	started     bool
+	polled      chan struct{}
+	polledOnce  sync.Once
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+type invocationState int
+
+const (
+	invocationQueued invocationState = iota
+	invocationDelivered
+	invocationComplete
+)
+
+type invocation struct {
+	payload []byte
+	state   invocationState
+	result  Result
+	done    chan struct{}
+}
+
+// startMu serialises Start, as the address of the Runtime API is provided to aws-lambda-go by the environment.
+var startMu sync.Mutex
+
+// NewRuntime starts a Runtime listening on a random port of the loopback interface.
+func NewRuntime(opts ...Option) *Runtime {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("lambdatest: failed to listen: %v", err))
+	}
+
+	r := &Runtime{
+		listener:    l,
+		timeout:     DefaultTimeout,
+		functionARN: DefaultFunctionARN,
+		invocations: map[string]*invocation{},
+		wake:        make(chan struct{}),
+		polled:      make(chan struct{}),
+		closed:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	go (&http.Server{Handler: r}).Serve(l)
+
+	return r
+}
+
+// Address returns the host and port of the Runtime, as expected in AWS_LAMBDA_RUNTIME_API.
+func (r *Runtime) Address() string {
+	return r.listener.Addr().String()
+}
+
+// Start runs the aws-lambda-go runtime loop against the Runtime in the background, returning once the loop has polled
+// for its first invocation. AWS_LAMBDA_RUNTIME_API is set only while the loop starts, and is then restored. Start may
+// only be called once for each Runtime.
+func (r *Runtime) Start(handler interface{}, opts ...lambda.Option) {
+	r.mu.Lock()
+	if r.started {
+		r.mu.Unlock()
+		panic("lambdatest: Runtime already started")
+	}
+	r.started = true
+	r.mu.Unlock()
+
+	startMu.Lock()
+	defer startMu.Unlock()
+
+	prev, set := os.LookupEnv(envRuntimeAPI)
+	_ = os.Setenv(envRuntimeAPI, r.Address())
+
+	defer func() {
+		if set {
+			_ = os.Setenv(envRuntimeAPI, prev)
+		} else {
+			_ = os.Unsetenv(envRuntimeAPI)
+		}
+	}()
+
+	go lambda.StartWithOptions(handler, opts...)
+
+	select {
+	case <-r.polled:
+	case <-r.closed:
+	}
+}
+
+// Enqueue queues a payload for invocation, returning the request ID which can be used to wait for the Result.
+func (r *Runtime) Enqueue(payload []byte) string {
+	id := newRequestID()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := &invocation{payload: payload, done: make(chan struct{})}
+	r.invocations[id] = i
+	r.queue = append(r.queue, i)
+	i.result.RequestID = id
+
+	close(r.wake)
+	r.wake = make(chan struct{})
+
+	return id
+}
+
+// Result waits for the Result of an enqueued request ID, until the context is done or the Runtime is closed.
+func (r *Runtime) Result(ctx context.Context, id string) (Result, error) {
+	r.mu.Lock()
+	i, ok := r.invocations[id]
+	r.mu.Unlock()
+
+	if !ok {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownRequest, id)
+	}
+
+	select {
+	case <-i.done:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return i.result, nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	case <-r.closed:
+		return Result{}, ErrClosed
+	}
+}
+
+// Invoke queues a payload for invocation and waits for its Result, see Enqueue and Result.
+func (r *Runtime) Invoke(ctx context.Context, payload []byte) (Result, error) {
+	return r.Result(ctx, r.Enqueue(payload))
+}
+
+// Results returns the Result of every completed invocation, in the order they were completed.
+func (r *Runtime) Results() []Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Result{}, r.results...)
+}
+
+// InitError returns the error reported by the function if it failed to initialise.
+func (r *Runtime) InitError() (*messages.InvokeResponse_Error, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.initError, r.initError != nil
+}
+
+// Close stops the Runtime from providing invocations, and stops accepting connections. Any runtime loop polling for an
+// invocation is left waiting, as aws-lambda-go exits the process if the Runtime API is unavailable.
+func (r *Runtime) Close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		_ = r.listener.Close()
+	})
+}
+
+// ServeHTTP implements http.Handler, providing the Runtime API.
+func (r *Runtime) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, apiPrefix)
+
+	switch {
+	case path == req.URL.Path:
+		writeError(w, http.StatusNotFound, "NotFound", "unknown path")
+	case path == "invocation/next" && req.Method == http.MethodGet:
+		r.next(w, req)
+	case path == "init/error" && req.Method == http.MethodPost:
+		r.reportInitError(w, req)
+	case strings.HasPrefix(path, "invocation/") && req.Method == http.MethodPost:
+		id, action, _ := strings.Cut(strings.TrimPrefix(path, "invocation/"), "/")
+
+		switch action {
+		case "response":
+			r.report(w, req, id, false)
+		case "error":
+			r.report(w, req, id, true)
+		default:
+			writeError(w, http.StatusNotFound, "NotFound", "unknown path")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", "unknown path")
+	}
+}
+
+// next provides the next queued invocation, blocking until one is available. Once closed it blocks until the client
+// goes away, rather than failing the request.
+func (r *Runtime) next(w http.ResponseWriter, req *http.Request) {
+	r.polledOnce.Do(func() {
+		close(r.polled)
+	})
+
+	for {
+		r.mu.Lock()
+
+		select {
+		case <-r.closed:
+			r.mu.Unlock()
+			<-req.Context().Done()
+			return
+		default:
+		}
+
+		if len(r.queue) > 0 {
+			i := r.queue[0]
+			r.queue = r.queue[1:]
+			i.state = invocationDelivered
+			i.result.Deadline = time.Now().Add(r.timeout)
+			r.mu.Unlock()
+
+			w.Header().Set(headerRequestID, i.result.RequestID)
+			w.Header().Set(headerDeadlineMS, strconv.FormatInt(i.result.Deadline.UnixMilli(), 10))
+			w.Header().Set(headerFunctionARN, r.functionARN)
+			w.Header().Set(headerTraceID, newTraceID())
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(i.payload)
+			return
+		}
+
+		wake := r.wake
+		r.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-r.closed:
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// report records the response or error of a delivered invocation.
+func (r *Runtime) report(w http.ResponseWriter, req *http.Request, id string, failed bool) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	var invokeErr *messages.InvokeResponse_Error
+	if failed {
+		invokeErr = &messages.InvokeResponse_Error{}
+
+		if err := json.Unmarshal(body, invokeErr); err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.invocations[id]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "InvalidRequestID", "unknown request id")
+		return
+	}
+
+	if i.state != invocationDelivered {
+		writeError(w, http.StatusForbidden, "InvalidStateTransition", "invocation is not in progress")
+		return
+	}
+
+	i.state = invocationComplete
+	i.result.ContentType = req.Header.Get("Content-Type")
+
+	if failed {
+		i.result.Error = invokeErr
+	} else {
+		i.result.Payload = body
+	}
+
+	r.results = append(r.results, i.result)
+	close(i.done)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// reportInitError records the error reported by a function that failed to initialise.
+func (r *Runtime) reportInitError(w http.ResponseWriter, req *http.Request) {
+	var invokeErr messages.InvokeResponse_Error
+
+	if err := json.NewDecoder(req.Body).Decode(&invokeErr); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return
+	}
+
+	r.mu.Lock()
+	r.initError = &invokeErr
+	r.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeError writes an error response in the form used by the Runtime API.
+func writeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(messages.InvokeResponse_Error{Type: errorType, Message: message})
+}
+
+// newRequestID generates a random request ID in the form of a UUID, as used by Lambda.
+func newRequestID() string {
+	b := randomHex(16)
+	return b[0:8] + "-" + b[8:12] + "-" + b[12:16] + "-" + b[16:20] + "-" + b[20:32]
+}
+
+// newTraceID generates a random X-Ray trace header, as provided by Lambda.
+func newTraceID() string {
+	return fmt.Sprintf("Root=1-%08x-%s;Parent=%s;Sampled=0", time.Now().Unix(), randomHex(12), randomHex(8))
+}
+
+// randomHex returns n random bytes, hex encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("lambdatest: failed to generate random bytes: %v", err))
+	}
+
+	return hex.EncodeToString(b)
+}
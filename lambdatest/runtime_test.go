@@ -0,0 +1,254 @@
+package lambdatest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/pwood/lambdawrap"
+	"github.com/pwood/lambdawrap/codec"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testInput struct {
+	Name string `json:"name"`
+}
+
+type testOutput struct {
+	Greeting string `json:"greeting"`
+}
+
+type testValidationError struct{}
+
+func (testValidationError) Error() string {
+	return "name is required"
+}
+
+func (testValidationError) LambdaErrorType() string {
+	return "ValidationError"
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestRuntime(t *testing.T) {
+	t.Run("an SQS chain is invoked end to end through lambda.Start", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		rt.Start(lambdawrap.Handler(lambdawrap.SQS(lambdawrap.DomainObject(func(_ context.Context, in testInput) (testOutput, error) {
+			if in.Name == "" {
+				return testOutput{}, testValidationError{}
+			}
+
+			return testOutput{Greeting: "hello " + in.Name}, nil
+		}, codec.JSON))))
+
+		res, err := rt.Invoke(testContext(t), []byte(`{"Records":[{"body":"{\"name\":\"world\"}"}]}`))
+		assert.NoError(t, err)
+		assert.Nil(t, res.Error)
+		assert.Equal(t, `{"greeting":"hello world"}`, string(res.Payload))
+		assert.Equal(t, "application/octet-stream", res.ContentType)
+
+		res, err = rt.Invoke(testContext(t), []byte(`{"Records":[{"body":"{}"}]}`))
+		assert.NoError(t, err)
+		assert.Nil(t, res.Payload)
+		assert.Equal(t, &messages.InvokeResponse_Error{Message: "SQS next: DomainObject next: name is required", Type: "ValidationError"}, res.Error)
+	})
+
+	t.Run("queued payloads are invoked in order and their results recorded", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		var first, second string
+
+		first = rt.Enqueue([]byte(`"one"`))
+		second = rt.Enqueue([]byte(`"two"`))
+
+		rt.Start(lambdawrap.Handler(func(_ context.Context, s string) ([]byte, error) {
+			return []byte(strings.ToUpper(s)), nil
+		}))
+
+		res, err := rt.Result(testContext(t), second)
+		assert.NoError(t, err)
+		assert.Equal(t, `"TWO"`, string(res.Payload))
+
+		results := rt.Results()
+		assert.Len(t, results, 2)
+		assert.Equal(t, first, results[0].RequestID)
+		assert.Equal(t, `"ONE"`, string(results[0].Payload))
+		assert.Equal(t, second, results[1].RequestID)
+	})
+
+	t.Run("the request ID, deadline and function ARN are provided to the function", func(t *testing.T) {
+		rt := NewRuntime(WithTimeout(time.Minute), WithFunctionARN("arn:aws:lambda:eu-west-1:123456789012:function:test"))
+		defer rt.Close()
+
+		var (
+			requestID, functionARN string
+			deadline               time.Time
+		)
+
+		rt.Start(lambdawrap.Handler(func(ctx context.Context, _ []byte) ([]byte, error) {
+			lc, _ := lambdacontext.FromContext(ctx)
+			requestID = lc.AwsRequestID
+			functionARN = lc.InvokedFunctionArn
+			deadline, _ = ctx.Deadline()
+			return nil, nil
+		}))
+
+		start := time.Now()
+
+		res, err := rt.Invoke(testContext(t), []byte(`{}`))
+		assert.NoError(t, err)
+		assert.Equal(t, res.RequestID, requestID)
+		assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, requestID)
+		assert.Equal(t, "arn:aws:lambda:eu-west-1:123456789012:function:test", functionARN)
+		assert.Equal(t, res.Deadline.UnixMilli(), deadline.UnixMilli())
+		assert.WithinDuration(t, start.Add(time.Minute), deadline, 5*time.Second)
+	})
+
+	t.Run("the environment is restored after starting", func(t *testing.T) {
+		t.Setenv(envRuntimeAPI, "previous")
+
+		rt := NewRuntime()
+		defer rt.Close()
+
+		rt.Start(lambdawrap.Handler(lambdawrap.Nop[[]byte]()))
+
+		assert.Equal(t, "previous", os.Getenv(envRuntimeAPI))
+	})
+
+	t.Run("starting twice panics", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		rt.Start(lambdawrap.Handler(lambdawrap.Nop[[]byte]()))
+
+		assert.Panics(t, func() {
+			rt.Start(lambdawrap.Handler(lambdawrap.Nop[[]byte]()))
+		})
+	})
+
+	t.Run("waiting for an unknown request ID returns an error", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		_, err := rt.Result(testContext(t), "unknown")
+		assert.True(t, errors.Is(err, ErrUnknownRequest))
+	})
+
+	t.Run("waiting for a result ends when the context is done or the runtime is closed", func(t *testing.T) {
+		rt := NewRuntime()
+		id := rt.Enqueue([]byte(`{}`))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := rt.Result(ctx, id)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		rt.Close()
+
+		_, err = rt.Result(context.Background(), id)
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
+
+func TestRuntime_ServeHTTP(t *testing.T) {
+	serve := func(rt *Runtime, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	t.Run("next provides the payload with the invocation headers", func(t *testing.T) {
+		rt := NewRuntime(WithTimeout(time.Minute))
+		defer rt.Close()
+
+		id := rt.Enqueue([]byte(`{"a":1}`))
+
+		w := serve(rt, http.MethodGet, "/2018-06-01/runtime/invocation/next", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `{"a":1}`, w.Body.String())
+		assert.Equal(t, id, w.Header().Get("Lambda-Runtime-Aws-Request-Id"))
+		assert.Equal(t, DefaultFunctionARN, w.Header().Get("Lambda-Runtime-Invoked-Function-Arn"))
+		assert.Regexp(t, `^Root=1-[0-9a-f]{8}-[0-9a-f]{24};Parent=[0-9a-f]{16};Sampled=0$`, w.Header().Get("Lambda-Runtime-Trace-Id"))
+		assert.Regexp(t, `^[0-9]+$`, w.Header().Get("Lambda-Runtime-Deadline-Ms"))
+	})
+
+	t.Run("an error is recorded for the invocation", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		id := rt.Enqueue([]byte(`{}`))
+		serve(rt, http.MethodGet, "/2018-06-01/runtime/invocation/next", "")
+
+		w := serve(rt, http.MethodPost, "/2018-06-01/runtime/invocation/"+id+"/error", `{"errorMessage":"failed","errorType":"Failure"}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		res, err := rt.Result(testContext(t), id)
+		assert.NoError(t, err)
+		assert.Equal(t, &messages.InvokeResponse_Error{Message: "failed", Type: "Failure"}, res.Error)
+	})
+
+	t.Run("a response for an invocation not in progress is rejected", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		id := rt.Enqueue([]byte(`{}`))
+
+		w := serve(rt, http.MethodPost, "/2018-06-01/runtime/invocation/"+id+"/response", `{}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		serve(rt, http.MethodGet, "/2018-06-01/runtime/invocation/next", "")
+
+		w = serve(rt, http.MethodPost, "/2018-06-01/runtime/invocation/"+id+"/response", `{}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		w = serve(rt, http.MethodPost, "/2018-06-01/runtime/invocation/"+id+"/response", `{}`)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("a response for an unknown request ID is rejected", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		w := serve(rt, http.MethodPost, "/2018-06-01/runtime/invocation/unknown/response", `{}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"errorMessage":"unknown request id","errorType":"InvalidRequestID"}`, w.Body.String())
+	})
+
+	t.Run("an init error is recorded", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		_, ok := rt.InitError()
+		assert.False(t, ok)
+
+		w := serve(rt, http.MethodPost, "/2018-06-01/runtime/init/error", `{"errorMessage":"no config","errorType":"InitError"}`)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		initErr, ok := rt.InitError()
+		assert.True(t, ok)
+		assert.Equal(t, &messages.InvokeResponse_Error{Message: "no config", Type: "InitError"}, initErr)
+	})
+
+	t.Run("unknown paths are not found", func(t *testing.T) {
+		rt := NewRuntime()
+		defer rt.Close()
+
+		assert.Equal(t, http.StatusNotFound, serve(rt, http.MethodGet, "/unknown", "").Code)
+		assert.Equal(t, http.StatusNotFound, serve(rt, http.MethodPost, "/2018-06-01/runtime/invocation/id/unknown", "").Code)
+	})
+}
@@ -8,18 +8,23 @@ import (
 
 // S3Notification provides a wrapper to iterate through multiple S3 records included in an events.S3Event. Default behaviour is
 // to concatenate the []byte output from each message, returning to the caller.
-func S3Notification(n func(context.Context, events.S3EventRecord) ([]byte, error)) func(context.Context, events.S3Event) ([]byte, error) {
-	return func(ctx context.Context, e events.S3Event) ([]byte, error) {
-		var ret []byte
+//
+// Records can be processed concurrently with WithConcurrency, and WithOrdered ensures that records for the same object
+// are processed in order.
+func S3Notification(n func(context.Context, events.S3EventRecord) ([]byte, error), opts ...BatchOption) func(context.Context, events.S3Event) ([]byte, error) {
+	o := newBatchOptions(opts)
 
-		for _, r := range e.Records {
-			if d, err := n(ctx, r); err != nil {
+	return func(ctx context.Context, e events.S3Event) ([]byte, error) {
+		data, errs := batchRun(ctx, o, true, len(e.Records), func(i int) string {
+			return e.Records[i].S3.Bucket.Name + "/" + e.Records[i].S3.Object.Key
+		}, func(ctx context.Context, i int) ([]byte, error) {
+			if d, err := n(ctx, e.Records[i]); err != nil {
 				return nil, fmt.Errorf("S3Notification next: %w", err)
 			} else {
-				ret = append(ret, d...)
+				return d, nil
 			}
-		}
+		})
 
-		return ret, nil
+		return batchConcat(o, data, errs)
 	}
 }
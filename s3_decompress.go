@@ -0,0 +1,141 @@
+package lambdawrap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	s3CompressionNone  = ""
+	s3CompressionGzip  = "gzip"
+	s3CompressionBzip2 = "bzip2"
+	s3CompressionZlib  = "zlib"
+)
+
+// S3Decompress consumes an io.Reader of a fetched S3 object and provides the next function with an io.Reader of the
+// decompressed object, it should be chained between S3Fetch and a reader such as S3ReadAll or S3Lines. Gzip, bzip2 and
+// zlib are supported, uncompressed objects are passed through unaltered.
+//
+// The compression is selected from the Content-Encoding of the S3ObjectMetadata if S3FetchWithMetadata was used, then
+// from the extension of the object key (.gz, .bz2 or .zlib), and finally by examining the first bytes of the object.
+// Detection of zlib from the first bytes is conservative, zlib objects should have a Content-Encoding or extension.
+//
+// Example:
+//
+//   S3Notification(S3FetchWithMetadata(S3Decompress(S3ReadAll(myFunc)), fetcher))
+func S3Decompress(n func(context.Context, io.Reader) ([]byte, error)) func(context.Context, io.Reader) ([]byte, error) {
+	return func(ctx context.Context, r io.Reader) ([]byte, error) {
+		br := bufio.NewReader(r)
+
+		compression := s3CompressionFromContext(ctx)
+		if compression == s3CompressionNone {
+			compression = s3CompressionSniff(br)
+		}
+
+		var dr io.Reader
+
+		switch compression {
+		case s3CompressionGzip:
+			if gr, err := gzip.NewReader(br); err != nil {
+				return nil, fmt.Errorf("S3Decompress gzip: %w", err)
+			} else {
+				defer gr.Close()
+				dr = gr
+			}
+		case s3CompressionBzip2:
+			dr = bzip2.NewReader(br)
+		case s3CompressionZlib:
+			if zr, err := zlib.NewReader(br); err != nil {
+				return nil, fmt.Errorf("S3Decompress zlib: %w", err)
+			} else {
+				defer zr.Close()
+				dr = zr
+			}
+		default:
+			dr = br
+		}
+
+		if d, err := n(ctx, dr); err != nil {
+			return nil, fmt.Errorf("S3Decompress next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
+
+// s3CompressionFromContext selects the compression from the Content-Encoding of the object metadata, or from the
+// extension of the object key.
+func s3CompressionFromContext(ctx context.Context) string {
+	if m, ok := S3ObjectMetadataFromContext(ctx); ok {
+		switch strings.ToLower(strings.TrimSpace(m.ContentEncoding)) {
+		case "gzip", "x-gzip":
+			return s3CompressionGzip
+		case "bzip2", "x-bzip2":
+			return s3CompressionBzip2
+		case "deflate", "zlib":
+			return s3CompressionZlib
+		}
+	}
+
+	if e, ok := S3EntityFromContext(ctx); ok {
+		key := e.Object.URLDecodedKey
+		if key == "" {
+			key = e.Object.Key
+		}
+
+		switch strings.ToLower(path.Ext(key)) {
+		case ".gz", ".gzip":
+			return s3CompressionGzip
+		case ".bz2":
+			return s3CompressionBzip2
+		case ".zlib", ".zz":
+			return s3CompressionZlib
+		}
+	}
+
+	return s3CompressionNone
+}
+
+// s3CompressionSniff selects the compression by examining the magic bytes at the start of the object. Bzip2 requires
+// the block size digit after "BZh". A zlib header starts with "x", so to avoid mistaking text for zlib only the four
+// headers without a preset dictionary are matched, and only if the start of the object is not valid UTF-8.
+func s3CompressionSniff(br *bufio.Reader) string {
+	magic, _ := br.Peek(s3SniffLength)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return s3CompressionGzip
+	case len(magic) >= 4 && bytes.HasPrefix(magic, []byte("BZh")) && magic[3] >= '1' && magic[3] <= '9':
+		return s3CompressionBzip2
+	case len(magic) >= 2 && magic[0] == 0x78 && bytes.IndexByte([]byte{0x01, 0x5e, 0x9c, 0xda}, magic[1]) >= 0 && !s3ValidUTF8Prefix(magic):
+		return s3CompressionZlib
+	}
+
+	return s3CompressionNone
+}
+
+// s3SniffLength is the number of bytes examined by s3CompressionSniff.
+const s3SniffLength = 512
+
+// s3ValidUTF8Prefix reports whether b is valid UTF-8, ignoring an incomplete rune at the end as b may be truncated.
+func s3ValidUTF8Prefix(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			return !utf8.FullRune(b)
+		}
+
+		b = b[size:]
+	}
+
+	return true
+}
@@ -0,0 +1,135 @@
+package lambdawrap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testBzip2 is "hello world" compressed with bzip2, the standard library can only decompress bzip2.
+const testBzip2 = "QlpoOTFBWSZTWUT3E3gAAAGRgEAABkSQgCAAIgM0hDAhtoFUJ4u5IpwoSCJ7ibwA"
+
+func testGzip(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	_, err := w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func testZlib(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	w := zlib.NewWriter(buf)
+	_, err := w.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestS3Decompress(t *testing.T) {
+	bz2, err := base64.StdEncoding.DecodeString(testBzip2)
+	assert.NoError(t, err)
+
+	next := S3ReadAll(func(_ context.Context, d []byte) ([]byte, error) {
+		return d, nil
+	})
+
+	withKey := func(key string) context.Context {
+		return context.WithValue(context.TODO(), contextKeyS3Entity, events.S3Entity{Object: events.S3Object{Key: key}})
+	}
+
+	withEncoding := func(enc string) context.Context {
+		return context.WithValue(context.TODO(), contextKeyS3ObjectMetadata, S3ObjectMetadata{ContentEncoding: enc})
+	}
+
+	t.Run("compression is detected from magic bytes", func(t *testing.T) {
+		for name, in := range map[string][]byte{"gzip": testGzip(t, "hello world"), "bzip2": bz2, "zlib": testZlib(t, "hello world")} {
+			d, err := S3Decompress(next)(context.TODO(), bytes.NewReader(in))
+			assert.NoError(t, err, name)
+			assert.Equal(t, "hello world", string(d), name)
+		}
+	})
+
+	t.Run("uncompressed data is passed through unaltered", func(t *testing.T) {
+		for _, in := range []string{"hello world", "xyz", "", "B", "x marks the spot", "x^2 + y^2", "x\x01", "BZh", "BZh is not bzip2"} {
+			d, err := S3Decompress(next)(context.TODO(), strings.NewReader(in))
+			assert.NoError(t, err)
+			assert.Equal(t, in, string(d))
+		}
+	})
+
+	t.Run("zlib streams with a preset dictionary are not detected", func(t *testing.T) {
+		in := append([]byte{0x78, 0x20}, bytes.Repeat([]byte{0xff}, 8)...)
+
+		d, err := S3Decompress(next)(context.TODO(), bytes.NewReader(in))
+		assert.NoError(t, err)
+		assert.Equal(t, in, d)
+	})
+
+	t.Run("compression is selected from the object key extension", func(t *testing.T) {
+		_, err := S3Decompress(next)(withKey("file.gz"), strings.NewReader("not gzip"))
+		assert.Error(t, err)
+
+		d, err := S3Decompress(next)(withKey("file.bz2"), bytes.NewReader(bz2))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(d))
+
+		d, err = S3Decompress(next)(withKey("file.zlib"), bytes.NewReader(testZlib(t, "hello world")))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(d))
+	})
+
+	t.Run("compression is selected from the content encoding", func(t *testing.T) {
+		_, err := S3Decompress(next)(withEncoding("gzip"), strings.NewReader("not gzip"))
+		assert.Error(t, err)
+
+		d, err := S3Decompress(next)(withEncoding("x-gzip"), bytes.NewReader(testGzip(t, "hello world")))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(d))
+
+		_, err = S3Decompress(next)(withEncoding("deflate"), strings.NewReader("not zlib"))
+		assert.Error(t, err)
+	})
+
+	t.Run("errors from next are propagated back", func(t *testing.T) {
+		expected := errors.New("expected")
+
+		n := func(_ context.Context, _ io.Reader) ([]byte, error) {
+			return nil, expected
+		}
+
+		_, err := S3Decompress(n)(context.TODO(), bytes.NewReader(testGzip(t, "hello world")))
+		assert.ErrorIs(t, err, expected)
+	})
+
+	t.Run("can be chained from S3FetchWithMetadata", func(t *testing.T) {
+		fetcher := func(_ context.Context, _ events.S3Entity) (io.ReadCloser, S3ObjectMetadata, error) {
+			return io.NopCloser(bytes.NewReader(testGzip(t, "hello world"))), S3ObjectMetadata{ContentEncoding: "gzip"}, nil
+		}
+
+		d, err := S3FetchWithMetadata(S3Decompress(next), fetcher)(context.TODO(), events.S3EventRecord{})
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(d))
+	})
+}
+
+func TestS3ValidUTF8Prefix(t *testing.T) {
+	t.Run("an incomplete rune at the end is ignored", func(t *testing.T) {
+		assert.True(t, s3ValidUTF8Prefix([]byte("caf\xc3")))
+		assert.True(t, s3ValidUTF8Prefix([]byte("café")))
+	})
+
+	t.Run("invalid bytes before the end are detected", func(t *testing.T) {
+		assert.False(t, s3ValidUTF8Prefix([]byte{0x78, 0x9c, 'a'}))
+		assert.False(t, s3ValidUTF8Prefix([]byte("caf\xc3a")))
+	})
+}
@@ -6,10 +6,17 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 type S3Fetcher func(context.Context, events.S3Entity) (io.ReadCloser, error)
 
+// S3MetadataFetcher is an S3Fetcher which also returns the metadata of the object, it is used by S3FetchWithMetadata.
+type S3MetadataFetcher func(context.Context, events.S3Entity) (io.ReadCloser, S3ObjectMetadata, error)
+
 // S3Fetch consumes an events.S3EventRecord and retrieves the object from S3, providing an io.Reader to the next
 // function. Default behaviour is to concatenate the []byte output from each message, returning to the caller.
 //
@@ -17,16 +24,75 @@ type S3Fetcher func(context.Context, events.S3Entity) (io.ReadCloser, error)
 // S3Fetcher interface. This may make this function seem very light, however it is done so due to the complexities
 // surrounding constructing S3 clients and the infinite combinations of configuration that might be needed.
 //
+// S3 event notifications provide the object key URL encoded, if the URLDecodedKey of the object has not already been
+// populated (as it is when the event is unmarshalled from JSON) it is decoded before the S3Fetcher is called. The raw
+// Key is left unaltered, S3Fetcher implementations should use URLDecodedKey.
+//
 // A copy of the event.S3Entity is added to the context, and can be extracted with S3EntityFromContext.
 //
 // A very basic S3 Fetcher implementation is included as impl.Fetcher, it is a submodule so will need to be imported
 // separately, this is to prevent the dependency of the AWS SDK leaking into lambdawrap.
 func S3Fetch(n func(context.Context, io.Reader) ([]byte, error), i S3Fetcher) func(context.Context, events.S3EventRecord) ([]byte, error) {
+	return s3Fetch(n, func(ctx context.Context, e events.S3Entity) (io.ReadCloser, S3ObjectMetadata, error) {
+		r, err := i(ctx, e)
+		return r, S3ObjectMetadata{}, err
+	}, false)
+}
+
+// S3FetchWithMetadata behaves as S3Fetch, however the S3MetadataFetcher also returns the metadata of the object, which
+// is added to the context and can be extracted with S3ObjectMetadataFromContext. The metadata is used by S3Decompress.
+//
+// impl.S3 can be used by adapting it with S3HeaderMetadataFetcher.
+func S3FetchWithMetadata(n func(context.Context, io.Reader) ([]byte, error), i S3MetadataFetcher) func(context.Context, events.S3EventRecord) ([]byte, error) {
+	return s3Fetch(n, i, true)
+}
+
+// S3ObjectHeaderer may be implemented by the io.ReadCloser returned from an S3Fetcher, to provide the metadata of the
+// object as the HTTP headers returned by S3. This permits an S3Fetcher which does not depend upon lambdawrap, such as
+// impl.S3, to provide metadata through S3HeaderMetadataFetcher.
+type S3ObjectHeaderer interface {
+	// Header returns the HTTP headers of the object, e.g. Content-Type, ETag and x-amz-meta- user defined metadata.
+	Header() http.Header
+}
+
+// S3HeaderMetadataFetcher adapts an S3Fetcher into an S3MetadataFetcher, parsing the S3ObjectMetadata from the headers
+// of the returned io.ReadCloser if it implements S3ObjectHeaderer, otherwise the metadata is empty.
+//
+// Example:
+//
+//   S3Notification(S3FetchWithMetadata(S3Decompress(S3ReadAll(myFunc)), S3HeaderMetadataFetcher(s3.Fetch)))
+func S3HeaderMetadataFetcher(f S3Fetcher) S3MetadataFetcher {
+	return func(ctx context.Context, e events.S3Entity) (io.ReadCloser, S3ObjectMetadata, error) {
+		r, err := f(ctx, e)
+		if err != nil {
+			return nil, S3ObjectMetadata{}, err
+		}
+
+		if h, ok := r.(S3ObjectHeaderer); ok {
+			return r, s3MetadataFromHeader(h.Header()), nil
+		}
+
+		return r, S3ObjectMetadata{}, nil
+	}
+}
+
+func s3Fetch(n func(context.Context, io.Reader) ([]byte, error), i S3MetadataFetcher, withMetadata bool) func(context.Context, events.S3EventRecord) ([]byte, error) {
 	return func(ctx context.Context, e events.S3EventRecord) ([]byte, error) {
-		if r, err := i(ctx, e.S3); err != nil {
+		if s, err := s3DecodeKey(e.S3); err != nil {
+			return nil, fmt.Errorf("s3 fetch key decode: %w", err)
+		} else {
+			e.S3 = s
+		}
+
+		if r, m, err := i(ctx, e.S3); err != nil {
 			return nil, fmt.Errorf("s3 fetch: %w", err)
 		} else {
 			ctx = context.WithValue(ctx, contextKeyS3Entity, e.S3)
+
+			if withMetadata {
+				ctx = context.WithValue(ctx, contextKeyS3ObjectMetadata, m)
+			}
+
 			d, err := n(ctx, r)
 
 			var closeErr error
@@ -38,7 +104,7 @@ func S3Fetch(n func(context.Context, io.Reader) ([]byte, error), i S3Fetcher) fu
 			if err != nil {
 				return nil, fmt.Errorf("s3 fetch next: %w", err)
 			} else if closeErr != nil {
-				return nil, fmt.Errorf("s3 fetch close: %w", err)
+				return nil, fmt.Errorf("s3 fetch close: %w", closeErr)
 			}
 
 			return d, nil
@@ -63,7 +129,8 @@ func S3ReadAll(n func(context.Context, []byte) ([]byte, error)) func(context.Con
 }
 
 // S3EntityFromContext retrieves an events.S3Entity from the context, for use after an S3Fetch wrap has been used if
-// the application needs the events.S3Entity that was downloaded.
+// the application needs the events.S3Entity that was downloaded. The object key is available both as provided by the
+// notification, URL encoded, in Object.Key and decoded in Object.URLDecodedKey.
 func S3EntityFromContext(ctx context.Context) (events.S3Entity, bool) {
 	if val := ctx.Value(contextKeyS3Entity); val != nil {
 		return val.(events.S3Entity), true
@@ -71,3 +138,51 @@ func S3EntityFromContext(ctx context.Context) (events.S3Entity, bool) {
 		return events.S3Entity{}, false
 	}
 }
+
+// s3UserMetadataPrefix is the prefix of the HTTP headers containing the user defined metadata of an object.
+const s3UserMetadataPrefix = "x-amz-meta-"
+
+// s3MetadataFromHeader parses the S3ObjectMetadata from the HTTP headers of an object, user defined metadata is taken
+// from the x-amz-meta- headers with the key lower cased, as S3 stores it. Headers which can not be parsed are ignored.
+func s3MetadataFromHeader(h http.Header) S3ObjectMetadata {
+	m := S3ObjectMetadata{
+		ContentEncoding: h.Get("Content-Encoding"),
+		ContentType:     h.Get("Content-Type"),
+		ETag:            h.Get("ETag"),
+		VersionID:       h.Get("X-Amz-Version-Id"),
+	}
+
+	if l, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil {
+		m.ContentLength = l
+	}
+
+	if t, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		m.LastModified = t
+	}
+
+	for k, v := range h {
+		if name := strings.ToLower(k); strings.HasPrefix(name, s3UserMetadataPrefix) && len(v) > 0 {
+			if m.Metadata == nil {
+				m.Metadata = map[string]string{}
+			}
+
+			m.Metadata[strings.TrimPrefix(name, s3UserMetadataPrefix)] = v[0]
+		}
+	}
+
+	return m
+}
+
+// s3DecodeKey populates the URLDecodedKey of the object from the URL encoded Key, if it has not already been populated.
+func s3DecodeKey(e events.S3Entity) (events.S3Entity, error) {
+	if e.Object.URLDecodedKey != "" || e.Object.Key == "" {
+		return e, nil
+	}
+
+	if k, err := url.QueryUnescape(e.Object.Key); err != nil {
+		return e, err
+	} else {
+		e.Object.URLDecodedKey = k
+		return e, nil
+	}
+}
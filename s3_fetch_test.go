@@ -5,9 +5,11 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"net/http"
 	"strings"
 	"testing"
 	"testing/iotest"
+	"time"
 )
 
 func TestS3Fetch(t *testing.T) {
@@ -59,6 +61,190 @@ func TestS3Fetch(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, []byte("data"), d)
 	})
+
+	t.Run("URL encoded object key is decoded before fetching, the raw key is retained", func(t *testing.T) {
+		var fetched events.S3Entity
+
+		s3Fetcher := func(_ context.Context, e events.S3Entity) (io.ReadCloser, error) {
+			fetched = e
+			return io.NopCloser(strings.NewReader("data")), nil
+		}
+
+		next := func(ctx context.Context, r io.Reader) ([]byte, error) {
+			e, ok := S3EntityFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "my+file%C3%A9.txt", e.Object.Key)
+			assert.Equal(t, "my fileé.txt", e.Object.URLDecodedKey)
+			return nil, nil
+		}
+
+		in := events.S3EventRecord{S3: events.S3Entity{Object: events.S3Object{Key: "my+file%C3%A9.txt"}}}
+
+		_, err := S3Fetch(next, s3Fetcher)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "my+file%C3%A9.txt", fetched.Object.Key)
+		assert.Equal(t, "my fileé.txt", fetched.Object.URLDecodedKey)
+	})
+
+	t.Run("an existing URL decoded key is not altered", func(t *testing.T) {
+		s3Fetcher := func(_ context.Context, e events.S3Entity) (io.ReadCloser, error) {
+			assert.Equal(t, "100% done", e.Object.URLDecodedKey)
+			return io.NopCloser(strings.NewReader("data")), nil
+		}
+
+		in := events.S3EventRecord{S3: events.S3Entity{Object: events.S3Object{Key: "100%25+done", URLDecodedKey: "100% done"}}}
+
+		_, err := S3Fetch(S3ReadAll(Nop[[]byte]()), s3Fetcher)(context.TODO(), in)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid URL encoding of the object key returns an error", func(t *testing.T) {
+		called := false
+
+		s3Fetcher := func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			called = true
+			return nil, nil
+		}
+
+		in := events.S3EventRecord{S3: events.S3Entity{Object: events.S3Object{Key: "bad%zz"}}}
+
+		_, err := S3Fetch(nil, s3Fetcher)(context.TODO(), in)
+		assert.Error(t, err)
+		assert.False(t, called)
+	})
+
+	t.Run("errors from closing the reader are propagated back", func(t *testing.T) {
+		s3Fetcher := func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			return errCloser{Reader: strings.NewReader("data")}, nil
+		}
+
+		_, err := S3Fetch(S3ReadAll(Nop[[]byte]()), s3Fetcher)(context.TODO(), events.S3EventRecord{})
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	})
+}
+
+func TestS3FetchWithMetadata(t *testing.T) {
+	t.Run("metadata returned by the fetcher is added to the context", func(t *testing.T) {
+		expected := S3ObjectMetadata{ContentEncoding: "gzip"}
+
+		s3Fetcher := func(_ context.Context, _ events.S3Entity) (io.ReadCloser, S3ObjectMetadata, error) {
+			return io.NopCloser(strings.NewReader("data")), expected, nil
+		}
+
+		next := func(ctx context.Context, r io.Reader) ([]byte, error) {
+			m, ok := S3ObjectMetadataFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, expected, m)
+			return io.ReadAll(r)
+		}
+
+		d, err := S3FetchWithMetadata(next, s3Fetcher)(context.TODO(), events.S3EventRecord{})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("data"), d)
+	})
+
+	t.Run("metadata is not present when S3Fetch is used", func(t *testing.T) {
+		s3Fetcher := func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("data")), nil
+		}
+
+		next := func(ctx context.Context, _ io.Reader) ([]byte, error) {
+			_, ok := S3ObjectMetadataFromContext(ctx)
+			assert.False(t, ok)
+			return nil, nil
+		}
+
+		_, err := S3Fetch(next, s3Fetcher)(context.TODO(), events.S3EventRecord{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors from closing the reader are propagated back", func(t *testing.T) {
+		s3Fetcher := func(_ context.Context, _ events.S3Entity) (io.ReadCloser, S3ObjectMetadata, error) {
+			return errCloser{Reader: strings.NewReader("data")}, S3ObjectMetadata{}, nil
+		}
+
+		_, err := S3FetchWithMetadata(S3ReadAll(Nop[[]byte]()), s3Fetcher)(context.TODO(), events.S3EventRecord{})
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	})
+}
+
+func TestS3HeaderMetadataFetcher(t *testing.T) {
+	t.Run("metadata is parsed from the headers of the object", func(t *testing.T) {
+		f := S3HeaderMetadataFetcher(func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			return headerCloser{ReadCloser: io.NopCloser(strings.NewReader("data")), header: http.Header{"Content-Encoding": {"gzip"}}}, nil
+		})
+
+		r, m, err := f(context.TODO(), events.S3Entity{})
+		assert.NoError(t, err)
+		assert.Equal(t, S3ObjectMetadata{ContentEncoding: "gzip"}, m)
+
+		d, _ := io.ReadAll(r)
+		assert.Equal(t, "data", string(d))
+	})
+
+	t.Run("every field of the metadata is parsed from the headers", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("Content-Encoding", "gzip")
+		h.Set("Content-Type", "text/csv")
+		h.Set("Content-Length", "1024")
+		h.Set("ETag", `"abc"`)
+		h.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		h.Set("X-Amz-Version-Id", "v1")
+		h.Set("X-Amz-Meta-Source", "export")
+		h.Set("X-Amz-Meta-Row-Count", "12")
+
+		f := S3HeaderMetadataFetcher(func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			return headerCloser{ReadCloser: io.NopCloser(strings.NewReader("data")), header: h}, nil
+		})
+
+		_, m, err := f(context.TODO(), events.S3Entity{})
+		assert.NoError(t, err)
+		assert.Equal(t, S3ObjectMetadata{
+			ContentEncoding: "gzip",
+			ContentType:     "text/csv",
+			ContentLength:   1024,
+			ETag:            `"abc"`,
+			LastModified:    time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+			Metadata:        map[string]string{"source": "export", "row-count": "12"},
+			VersionID:       "v1",
+		}, m)
+	})
+
+	t.Run("metadata is empty if the object does not provide headers", func(t *testing.T) {
+		f := S3HeaderMetadataFetcher(func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader("data")), nil
+		})
+
+		_, m, err := f(context.TODO(), events.S3Entity{})
+		assert.NoError(t, err)
+		assert.Equal(t, S3ObjectMetadata{}, m)
+	})
+
+	t.Run("errors from the fetcher are returned", func(t *testing.T) {
+		f := S3HeaderMetadataFetcher(func(_ context.Context, _ events.S3Entity) (io.ReadCloser, error) {
+			return nil, io.ErrUnexpectedEOF
+		})
+
+		_, _, err := f(context.TODO(), events.S3Entity{})
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+type headerCloser struct {
+	io.ReadCloser
+	header http.Header
+}
+
+func (h headerCloser) Header() http.Header {
+	return h.header
+}
+
+type errCloser struct {
+	io.Reader
+}
+
+func (errCloser) Close() error {
+	return io.ErrClosedPipe
 }
 
 func TestReadAll(t *testing.T) {
@@ -0,0 +1,166 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+// ErrS3ETagMismatch is wrapped by the error returned from S3CheckETag when the fetched object does not match the
+// object in the notification.
+var ErrS3ETagMismatch = errors.New("s3 etag mismatch")
+
+// ErrS3ObjectTooLarge is wrapped by the error returned from S3MaxSize when the object exceeds the maximum size.
+var ErrS3ObjectTooLarge = errors.New("s3 object too large")
+
+// S3ObjectMetadata contains metadata of an S3 object returned by an S3MetadataFetcher.
+type S3ObjectMetadata struct {
+	// ContentEncoding is the Content-Encoding of the object, e.g. gzip.
+	ContentEncoding string
+	// ContentType is the Content-Type of the object, e.g. application/json.
+	ContentType string
+	// ContentLength is the size of the object in bytes.
+	ContentLength int64
+	// ETag is the entity tag of the object, it may be surrounded by quotes.
+	ETag string
+	// LastModified is the time the object was last modified.
+	LastModified time.Time
+	// Metadata contains the user defined metadata of the object, without the x-amz-meta- prefix.
+	Metadata map[string]string
+	// VersionID is the version of the object, if versioning is enabled on the bucket.
+	VersionID string
+}
+
+// S3ObjectMetadataFromContext retrieves the S3ObjectMetadata from the context, for use after an S3FetchWithMetadata
+// wrap has been used if the application needs the metadata of the object that was downloaded.
+func S3ObjectMetadataFromContext(ctx context.Context) (S3ObjectMetadata, bool) {
+	if val := ctx.Value(contextKeyS3ObjectMetadata); val != nil {
+		return val.(S3ObjectMetadata), true
+	} else {
+		return S3ObjectMetadata{}, false
+	}
+}
+
+// S3CheckETag consumes the io.Reader of a fetched S3 object, and checks that the ETag of the fetched object matches
+// the ETag in the notification before calling next, this detects an object that has been overwritten since the
+// notification was sent. It must be chained from S3FetchWithMetadata, if the notification has no ETag the check is
+// skipped. A mismatch returns an error wrapping ErrS3ETagMismatch.
+//
+// Example:
+//
+//   S3Notification(S3FetchWithMetadata(S3CheckETag(S3ReadAll(myFunc)), fetcher))
+func S3CheckETag(n func(context.Context, io.Reader) ([]byte, error)) func(context.Context, io.Reader) ([]byte, error) {
+	return func(ctx context.Context, r io.Reader) ([]byte, error) {
+		m, ok := S3ObjectMetadataFromContext(ctx)
+		if !ok {
+			return nil, errors.New("S3CheckETag: no object metadata on context")
+		}
+
+		if e, ok := S3EntityFromContext(ctx); ok && e.Object.ETag != "" {
+			if expected, actual := strings.Trim(e.Object.ETag, `"`), strings.Trim(m.ETag, `"`); expected != actual {
+				return nil, fmt.Errorf("S3CheckETag: %w: expected %s, fetched %s", ErrS3ETagMismatch, expected, actual)
+			}
+		}
+
+		if d, err := n(ctx, r); err != nil {
+			return nil, fmt.Errorf("S3CheckETag next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
+
+// S3MaxSize consumes the io.Reader of a fetched S3 object, and returns an error wrapping ErrS3ObjectTooLarge if the
+// object is larger than max bytes. The size is checked before calling next using the S3ObjectMetadata if
+// S3FetchWithMetadata was used, otherwise the size in the notification. The reader provided to next also returns the
+// error if more than max bytes are read, in case neither size was available.
+func S3MaxSize(n func(context.Context, io.Reader) ([]byte, error), max int64) func(context.Context, io.Reader) ([]byte, error) {
+	return func(ctx context.Context, r io.Reader) ([]byte, error) {
+		size := int64(0)
+
+		if m, ok := S3ObjectMetadataFromContext(ctx); ok && m.ContentLength > 0 {
+			size = m.ContentLength
+		} else if e, ok := S3EntityFromContext(ctx); ok {
+			size = e.Object.Size
+		}
+
+		if size > max {
+			return nil, fmt.Errorf("S3MaxSize: %w: %d bytes exceeds %d", ErrS3ObjectTooLarge, size, max)
+		}
+
+		if d, err := n(ctx, &s3LimitReader{r: r, remaining: max}); err != nil {
+			return nil, fmt.Errorf("S3MaxSize next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
+
+// S3ContentTypeEquals provides a function for use with Filter or Match, which matches if the Content-Type of the
+// fetched object is one of types. Parameters such as charset are ignored. It must be chained from S3FetchWithMetadata.
+func S3ContentTypeEquals[O any](types ...string) func(context.Context, O) (bool, error) {
+	return func(ctx context.Context, o O) (bool, error) {
+		ct := S3ContentType[O]()(ctx, o)
+
+		for _, t := range types {
+			if strings.EqualFold(ct, t) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+}
+
+// S3ContentType provides a function for use with SwitchContext, which selects on the media type of the Content-Type of
+// the fetched object, without any parameters. An empty string is returned if there is no S3ObjectMetadata.
+//
+// Example:
+//
+//   S3FetchWithMetadata(SwitchContext(S3ContentType[io.Reader](), map[string]func(context.Context, io.Reader) ([]byte, error){
+//     "text/csv":             S3CSV(myCSVFunc),
+//     "application/x-ndjson": S3NDJSON(myJSONFunc, codec.JSON),
+//   }), fetcher)
+func S3ContentType[O any]() func(context.Context, O) string {
+	return func(ctx context.Context, _ O) string {
+		m, ok := S3ObjectMetadataFromContext(ctx)
+		if !ok {
+			return ""
+		}
+
+		if mt, _, err := mime.ParseMediaType(m.ContentType); err == nil {
+			return mt
+		}
+
+		return strings.ToLower(strings.TrimSpace(m.ContentType))
+	}
+}
+
+// s3LimitReader returns ErrS3ObjectTooLarge if more than remaining bytes are read.
+type s3LimitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *s3LimitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrS3ObjectTooLarge
+	}
+
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrS3ObjectTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,145 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func testS3Context(e events.S3Object, m *S3ObjectMetadata) context.Context {
+	ctx := context.WithValue(context.TODO(), contextKeyS3Entity, events.S3Entity{Object: e})
+
+	if m != nil {
+		ctx = context.WithValue(ctx, contextKeyS3ObjectMetadata, *m)
+	}
+
+	return ctx
+}
+
+func TestS3CheckETag(t *testing.T) {
+	next := S3ReadAll(func(_ context.Context, d []byte) ([]byte, error) {
+		return d, nil
+	})
+
+	t.Run("calls next when the fetched ETag matches the notification, ignoring quotes", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{ETag: "abc"}, &S3ObjectMetadata{ETag: `"abc"`})
+
+		d, err := S3CheckETag(next)(ctx, strings.NewReader("data"))
+		assert.NoError(t, err)
+		assert.Equal(t, "data", string(d))
+	})
+
+	t.Run("returns an error when the fetched ETag does not match the notification", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{ETag: "abc"}, &S3ObjectMetadata{ETag: `"def"`})
+
+		_, err := S3CheckETag(next)(ctx, strings.NewReader("data"))
+		assert.ErrorIs(t, err, ErrS3ETagMismatch)
+	})
+
+	t.Run("skips the check if the notification has no ETag", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{}, &S3ObjectMetadata{ETag: `"def"`})
+
+		_, err := S3CheckETag(next)(ctx, strings.NewReader("data"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("returns an error if there is no metadata on the context", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{ETag: "abc"}, nil)
+
+		_, err := S3CheckETag(next)(ctx, strings.NewReader("data"))
+		assert.Error(t, err)
+	})
+}
+
+func TestS3MaxSize(t *testing.T) {
+	next := S3ReadAll(func(_ context.Context, d []byte) ([]byte, error) {
+		return d, nil
+	})
+
+	t.Run("returns an error if the content length exceeds the maximum", func(t *testing.T) {
+		called := false
+
+		n := func(_ context.Context, _ io.Reader) ([]byte, error) {
+			called = true
+			return nil, nil
+		}
+
+		ctx := testS3Context(events.S3Object{Size: 1}, &S3ObjectMetadata{ContentLength: 11})
+
+		_, err := S3MaxSize(n, 10)(ctx, strings.NewReader("data"))
+		assert.ErrorIs(t, err, ErrS3ObjectTooLarge)
+		assert.False(t, called)
+	})
+
+	t.Run("falls back to the size in the notification", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{Size: 11}, nil)
+
+		_, err := S3MaxSize(next, 10)(ctx, strings.NewReader("data"))
+		assert.ErrorIs(t, err, ErrS3ObjectTooLarge)
+	})
+
+	t.Run("objects within the maximum are read in full", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{Size: 4}, &S3ObjectMetadata{ContentLength: 4})
+
+		d, err := S3MaxSize(next, 4)(ctx, strings.NewReader("data"))
+		assert.NoError(t, err)
+		assert.Equal(t, "data", string(d))
+	})
+
+	t.Run("reading beyond the maximum returns an error when the size is unknown", func(t *testing.T) {
+		_, err := S3MaxSize(next, 3)(context.TODO(), strings.NewReader("data"))
+		assert.ErrorIs(t, err, ErrS3ObjectTooLarge)
+	})
+}
+
+func TestS3ContentType(t *testing.T) {
+	t.Run("provides the media type without parameters", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{}, &S3ObjectMetadata{ContentType: "Text/CSV; charset=utf-8"})
+		assert.Equal(t, "text/csv", S3ContentType[io.Reader]()(ctx, nil))
+	})
+
+	t.Run("provides an empty string with no metadata", func(t *testing.T) {
+		assert.Equal(t, "", S3ContentType[io.Reader]()(context.TODO(), nil))
+	})
+
+	t.Run("can be used with SwitchContext", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{}, &S3ObjectMetadata{ContentType: "application/json"})
+
+		d, err := SwitchContext(S3ContentType[io.Reader](), map[string]func(context.Context, io.Reader) ([]byte, error){
+			"application/json": func(_ context.Context, _ io.Reader) ([]byte, error) { return []byte("json"), nil },
+			"text/csv":         func(_ context.Context, _ io.Reader) ([]byte, error) { return []byte("csv"), nil },
+		})(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "json", string(d))
+	})
+}
+
+func TestS3ContentTypeEquals(t *testing.T) {
+	t.Run("matches any of the types provided", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{}, &S3ObjectMetadata{ContentType: "text/csv"})
+
+		ok, err := S3ContentTypeEquals[io.Reader]("application/json", "text/csv")(ctx, nil)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = S3ContentTypeEquals[io.Reader]("application/json")(ctx, nil)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("can be used with Filter", func(t *testing.T) {
+		ctx := testS3Context(events.S3Object{}, &S3ObjectMetadata{ContentType: "text/plain"})
+		expected := errors.New("expected")
+
+		n := func(_ context.Context, _ io.Reader) ([]byte, error) {
+			return nil, expected
+		}
+
+		_, err := Filter(S3ContentTypeEquals[io.Reader]("text/csv"), n)(ctx, nil)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,289 @@
+package lambdawrap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// S3Lines consumes an io.Reader and calls the next function once per line, as an alternative to S3ReadAll which does
+// not hold the whole object in memory. Line endings (\n or \r\n) are removed, lines have no length limit. Default
+// behaviour is to concatenate the []byte output from each line, returning to the caller, processing stops at the first
+// error.
+//
+// The line number, starting at 1, is added to the context, and can be extracted with S3LineNumberFromContext.
+//
+// Example:
+//
+//   S3Notification(S3Fetch(S3Lines(myFunc), fetcher))
+func S3Lines(n func(context.Context, []byte) ([]byte, error)) func(context.Context, io.Reader) ([]byte, error) {
+	return func(ctx context.Context, r io.Reader) ([]byte, error) {
+		return s3EachLine(ctx, r, func(ctx context.Context, line int, d []byte) ([]byte, error) {
+			if d, err := n(ctx, d); err != nil {
+				return nil, fmt.Errorf("S3Lines line %d next: %w", line, err)
+			} else {
+				return d, nil
+			}
+		})
+	}
+}
+
+// S3NDJSON consumes an io.Reader of newline delimited records, such as NDJSON, decoding each line with the Codec and
+// calling the next function once per record. Blank lines are skipped. Default behaviour is to concatenate the []byte
+// output from each record, returning to the caller, processing stops at the first error.
+//
+// The line number of the record, starting at 1, is added to the context, and can be extracted with
+// S3LineNumberFromContext.
+//
+// Example:
+//
+//   S3Notification(S3Fetch(S3NDJSON(myFunc, codec.JSON), fetcher))
+func S3NDJSON[I any](n func(context.Context, I) ([]byte, error), c Codec) func(context.Context, io.Reader) ([]byte, error) {
+	return func(ctx context.Context, r io.Reader) ([]byte, error) {
+		return s3EachLine(ctx, r, func(ctx context.Context, line int, d []byte) ([]byte, error) {
+			if len(bytes.TrimSpace(d)) == 0 {
+				return nil, nil
+			}
+
+			in := new(I)
+
+			if err := c.Unmarshal(d, in); err != nil {
+				return nil, fmt.Errorf("S3NDJSON line %d codec unmarshal failure: %w", line, err)
+			}
+
+			if d, err := n(ctx, *in); err != nil {
+				return nil, fmt.Errorf("S3NDJSON line %d next: %w", line, err)
+			} else {
+				return d, nil
+			}
+		})
+	}
+}
+
+// S3CSV consumes an io.Reader of CSV, the first row of which must be a header, and calls the next function once per
+// row. Each row is mapped to O by header name, O may be a map[string]string or a struct. Struct fields are matched
+// using a `csv:"name"` tag, or the field name if there is no tag, a tag of "-" ignores the field. Fields may be strings,
+// bools, integers, floats or implement encoding.TextUnmarshaler, empty values leave the field as its zero value, and
+// columns without a matching field are ignored. Default behaviour is to concatenate the []byte output from each row,
+// returning to the caller, processing stops at the first error.
+//
+// The line number the row starts on, starting at 1 for the header, is added to the context, and can be extracted with
+// S3LineNumberFromContext.
+//
+// Example:
+//
+//   type row struct {
+//     ID   string `csv:"id"`
+//     Cost float64 `csv:"cost"`
+//   }
+//
+//   S3Notification(S3Fetch(S3CSV(func(ctx context.Context, r row) ([]byte, error) { ... }), fetcher))
+func S3CSV[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, io.Reader) ([]byte, error) {
+	return func(ctx context.Context, r io.Reader) ([]byte, error) {
+		cr := csv.NewReader(r)
+		cr.ReuseRecord = true
+
+		header, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("S3CSV header read: %w", err)
+		}
+
+		decode, err := csvRowDecoder[O](header)
+		if err != nil {
+			return nil, fmt.Errorf("S3CSV: %w", err)
+		}
+
+		var ret []byte
+
+		for {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return ret, nil
+			} else if err != nil {
+				return nil, fmt.Errorf("S3CSV read: %w", err)
+			}
+
+			line, _ := cr.FieldPos(0)
+
+			out := new(O)
+
+			if err := decode(row, out); err != nil {
+				return nil, fmt.Errorf("S3CSV line %d decode: %w", line, err)
+			}
+
+			if d, err := n(context.WithValue(ctx, contextKeyS3LineNumber, line), *out); err != nil {
+				return nil, fmt.Errorf("S3CSV line %d next: %w", line, err)
+			} else {
+				ret = append(ret, d...)
+			}
+		}
+	}
+}
+
+// S3LineNumberFromContext retrieves the line number being processed from the context, for use after an S3Lines,
+// S3NDJSON or S3CSV wrap has been used if the application needs to report the location of a problem.
+func S3LineNumberFromContext(ctx context.Context) (int, bool) {
+	if val := ctx.Value(contextKeyS3LineNumber); val != nil {
+		return val.(int), true
+	} else {
+		return 0, false
+	}
+}
+
+// s3EachLine reads r line by line, calling fn with the line number on the context, and concatenating the output.
+func s3EachLine(ctx context.Context, r io.Reader, fn func(context.Context, int, []byte) ([]byte, error)) ([]byte, error) {
+	br := bufio.NewReader(r)
+
+	var ret []byte
+
+	for line := 1; ; line++ {
+		d, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("line %d read: %w", line, err)
+		}
+
+		if len(d) == 0 && err != nil {
+			return ret, nil
+		}
+
+		d = bytes.TrimSuffix(bytes.TrimSuffix(d, []byte("\n")), []byte("\r"))
+
+		if out, fnErr := fn(context.WithValue(ctx, contextKeyS3LineNumber, line), line, d); fnErr != nil {
+			return nil, fnErr
+		} else {
+			ret = append(ret, out...)
+		}
+
+		if err != nil {
+			return ret, nil
+		}
+	}
+}
+
+// csvRowDecoder provides a function to map a CSV row onto O by header name, O must be a map[string]string or a struct.
+// The mapping of columns to struct fields is built once from the header, rather than for every row.
+func csvRowDecoder[O any](header []string) (func([]string, *O) error, error) {
+	header = append([]string(nil), header...)
+
+	if _, ok := any(new(O)).(*map[string]string); ok {
+		return func(row []string, out *O) error {
+			m := make(map[string]string, len(header))
+
+			for i, h := range header {
+				if i < len(row) {
+					m[h] = row[i]
+				}
+			}
+
+			*any(out).(*map[string]string) = m
+			return nil
+		}, nil
+	}
+
+	t := reflect.TypeOf(new(O)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+
+	fields := csvFieldIndex(t)
+	columns := make([]int, len(header))
+
+	for i, h := range header {
+		if idx, ok := fields[h]; ok {
+			columns[i] = idx
+		} else {
+			columns[i] = -1
+		}
+	}
+
+	return func(row []string, out *O) error {
+		rv := reflect.ValueOf(out).Elem()
+
+		for i, idx := range columns {
+			if idx < 0 || i >= len(row) || row[i] == "" {
+				continue
+			}
+
+			if err := csvSetField(rv.Field(idx), row[i]); err != nil {
+				return fmt.Errorf("column %q: %w", header[i], err)
+			}
+		}
+
+		return nil
+	}, nil
+}
+
+// csvFieldIndex maps column names to the index of the struct field they populate.
+func csvFieldIndex(t reflect.Type) map[string]int {
+	ret := map[string]int{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag = strings.Split(tag, ",")[0]; tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+		}
+
+		ret[name] = i
+	}
+
+	return ret
+}
+
+// csvSetField parses s into the field based upon its type.
+func csvSetField(f reflect.Value, s string) error {
+	if tu, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(fl)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.Type())
+	}
+
+	return nil
+}
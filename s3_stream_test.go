@@ -0,0 +1,213 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/pwood/lambdawrap/codec"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestS3Lines(t *testing.T) {
+	t.Run("next is called once per line, line endings are removed and the result is aggregated", func(t *testing.T) {
+		var lines []int
+
+		next := func(ctx context.Context, d []byte) ([]byte, error) {
+			l, _ := S3LineNumberFromContext(ctx)
+			lines = append(lines, l)
+			return append(d, '|'), nil
+		}
+
+		d, err := S3Lines(next)(context.TODO(), strings.NewReader("a\r\nb\n\nc"))
+		assert.NoError(t, err)
+		assert.Equal(t, "a|b||c|", string(d))
+		assert.Equal(t, []int{1, 2, 3, 4}, lines)
+	})
+
+	t.Run("a trailing newline does not produce an additional line", func(t *testing.T) {
+		count := 0
+
+		next := func(_ context.Context, _ []byte) ([]byte, error) {
+			count++
+			return nil, nil
+		}
+
+		_, err := S3Lines(next)(context.TODO(), strings.NewReader("a\nb\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("lines longer than the read buffer are handled", func(t *testing.T) {
+		long := strings.Repeat("x", 1<<20)
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			assert.Equal(t, long, string(d))
+			return nil, nil
+		}
+
+		_, err := S3Lines(next)(context.TODO(), strings.NewReader(long+"\n"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors from next stop processing and include the line number", func(t *testing.T) {
+		expected := errors.New("expected")
+		count := 0
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			count++
+			if string(d) == "b" {
+				return nil, expected
+			}
+			return nil, nil
+		}
+
+		_, err := S3Lines(next)(context.TODO(), strings.NewReader("a\nb\nc"))
+		assert.ErrorIs(t, err, expected)
+		assert.Contains(t, err.Error(), "line 2")
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("errors from reading are propagated back", func(t *testing.T) {
+		_, err := S3Lines(nil)(context.TODO(), iotest.ErrReader(io.ErrUnexpectedEOF))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func TestS3NDJSON(t *testing.T) {
+	type record struct {
+		ID int `json:"id"`
+	}
+
+	t.Run("each record is decoded with the codec, blank lines are skipped", func(t *testing.T) {
+		var ids, lines []int
+
+		next := func(ctx context.Context, r record) ([]byte, error) {
+			l, _ := S3LineNumberFromContext(ctx)
+			ids = append(ids, r.ID)
+			lines = append(lines, l)
+			return nil, nil
+		}
+
+		_, err := S3NDJSON(next, codec.JSON)(context.TODO(), strings.NewReader("{\"id\":1}\n\n  \n{\"id\":2}\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, ids)
+		assert.Equal(t, []int{1, 4}, lines)
+	})
+
+	t.Run("decode errors include the line number", func(t *testing.T) {
+		next := func(_ context.Context, _ record) ([]byte, error) {
+			return nil, nil
+		}
+
+		_, err := S3NDJSON(next, codec.JSON)(context.TODO(), strings.NewReader("{\"id\":1}\nnot json\n"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "line 2")
+	})
+
+	t.Run("errors from next are propagated back", func(t *testing.T) {
+		expected := errors.New("expected")
+
+		next := func(_ context.Context, _ record) ([]byte, error) {
+			return nil, expected
+		}
+
+		_, err := S3NDJSON(next, codec.JSON)(context.TODO(), strings.NewReader("{\"id\":1}"))
+		assert.ErrorIs(t, err, expected)
+	})
+}
+
+func TestS3CSV(t *testing.T) {
+	type row struct {
+		ID      string    `csv:"id"`
+		Count   int       `csv:"count"`
+		Cost    float64   `csv:"cost"`
+		Active  bool      `csv:"active"`
+		When    time.Time `csv:"when"`
+		Name    string
+		Ignored string `csv:"-"`
+	}
+
+	t.Run("rows are mapped to a struct by header name", func(t *testing.T) {
+		var rows []row
+		var lines []int
+
+		next := func(ctx context.Context, r row) ([]byte, error) {
+			l, _ := S3LineNumberFromContext(ctx)
+			rows = append(rows, r)
+			lines = append(lines, l)
+			return []byte(r.ID), nil
+		}
+
+		in := "cost,id,count,active,when,Name,Ignored,extra\n" +
+			"1.5,a,3,true,2022-01-02T03:04:05Z,alpha,x,y\n" +
+			"\"2\",\"b\nc\",,false,,,,\n"
+
+		d, err := S3CSV(next)(context.TODO(), strings.NewReader(in))
+		assert.NoError(t, err)
+		assert.Equal(t, "ab\nc", string(d))
+		assert.Equal(t, []row{
+			{ID: "a", Count: 3, Cost: 1.5, Active: true, When: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), Name: "alpha"},
+			{ID: "b\nc", Cost: 2},
+		}, rows)
+		assert.Equal(t, []int{2, 3}, lines)
+	})
+
+	t.Run("rows can be mapped to a map[string]string", func(t *testing.T) {
+		var rows []map[string]string
+
+		next := func(_ context.Context, r map[string]string) ([]byte, error) {
+			rows = append(rows, r)
+			return nil, nil
+		}
+
+		_, err := S3CSV(next)(context.TODO(), strings.NewReader("a,b\n1,2\n3,4\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, []map[string]string{{"a": "1", "b": "2"}, {"a": "3", "b": "4"}}, rows)
+	})
+
+	t.Run("decode errors include the line number", func(t *testing.T) {
+		next := func(_ context.Context, _ row) ([]byte, error) {
+			return nil, nil
+		}
+
+		_, err := S3CSV(next)(context.TODO(), strings.NewReader("id,count\na,1\nb,two\n"))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "line 3")
+		assert.Contains(t, err.Error(), "count")
+	})
+
+	t.Run("errors from next stop processing", func(t *testing.T) {
+		expected := errors.New("expected")
+		count := 0
+
+		next := func(_ context.Context, _ row) ([]byte, error) {
+			count++
+			return nil, expected
+		}
+
+		_, err := S3CSV(next)(context.TODO(), strings.NewReader("id\na\nb\n"))
+		assert.ErrorIs(t, err, expected)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("a missing header returns an error", func(t *testing.T) {
+		_, err := S3CSV(Nop[row]())(context.TODO(), strings.NewReader(""))
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("an unsupported type returns an error once the header is read", func(t *testing.T) {
+		called := false
+		next := func(_ context.Context, _ int) ([]byte, error) {
+			called = true
+			return nil, nil
+		}
+
+		_, err := S3CSV(next)(context.TODO(), strings.NewReader("id\n1\n"))
+		assert.ErrorContains(t, err, "unsupported type int")
+		assert.False(t, called)
+	})
+}
@@ -15,24 +15,37 @@ import (
 // encodings other than JSON.
 //
 // Do not use this directly for domain objects, use DomainObject.
-func SNS[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, events.SNSEvent) ([]byte, error) {
+//
+// The topic ARN is added to the context and can be extracted with SNSTopicARNFromContext, the full events.SNSEntity
+// including subject, message ID, timestamp and message attributes can be extracted with SNSEntityFromContext.
+//
+// Records can be processed concurrently with WithConcurrency.
+func SNS[O any](n func(context.Context, O) ([]byte, error), opts ...BatchOption) func(context.Context, events.SNSEvent) ([]byte, error) {
+	o := newBatchOptions(opts)
+
 	return func(ctx context.Context, e events.SNSEvent) ([]byte, error) {
-		var ret []byte
+		data, errs := batchRun(ctx, o, true, len(e.Records), func(i int) string {
+			return e.Records[i].SNS.TopicArn
+		}, func(ctx context.Context, i int) ([]byte, error) {
+			return snsRecord(ctx, e.Records[i], n)
+		})
 
-		for _, r := range e.Records {
-			ctx = context.WithValue(ctx, contextKeySNSARN, r.SNS.TopicArn)
-			if p, err := sliceStringOrUnmarshal[O]([]byte(r.SNS.Message)); err != nil {
-				return nil, fmt.Errorf("SNS unmarshal: %w", err)
-			} else {
-				if d, err := n(ctx, p); err != nil {
-					return nil, fmt.Errorf("SNS next: %w", err)
-				} else {
-					ret = append(ret, d...)
-				}
-			}
-		}
+		return batchConcat(o, data, errs)
+	}
+}
+
+func snsRecord[O any](ctx context.Context, r events.SNSEventRecord, n func(context.Context, O) ([]byte, error)) ([]byte, error) {
+	ctx = context.WithValue(ctx, contextKeySNSARN, r.SNS.TopicArn)
+	ctx = context.WithValue(ctx, contextKeySNSEntity, r.SNS)
 
-		return ret, nil
+	if p, err := sliceStringOrUnmarshal[O]([]byte(r.SNS.Message)); err != nil {
+		return nil, fmt.Errorf("SNS unmarshal: %w", err)
+	} else {
+		if d, err := n(ctx, p); err != nil {
+			return nil, fmt.Errorf("SNS next: %w", err)
+		} else {
+			return d, nil
+		}
 	}
 }
 
@@ -0,0 +1,101 @@
+package lambdawrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const snsNotificationType = "Notification"
+
+// SNSEnvelope consumes a []byte which may be an SNS notification envelope, as delivered to an SQS queue subscribed to an
+// SNS topic without raw message delivery, or to an HTTP(S) endpoint. If an envelope is detected (a JSON object with a
+// Type of Notification, a TopicArn and a Message) the Message is unwrapped and provided to next, otherwise the data is
+// provided to next unaltered. This permits the same chain to be used regardless of whether raw message delivery is
+// enabled on the subscription.
+//
+// When an envelope is unwrapped, the notification is added to the context in the same manner as the SNS wrap, and can
+// be extracted with SNSTopicARNFromContext, SNSEntityFromContext and the other SNS accessors.
+//
+// SNSEnvelope will attempt to unmarshal any destination structure with JSON in the same way as SNS. It is recommended
+// you use DomainObject instead.
+//
+// Example:
+//
+//   SQS(SNSEnvelope(DomainObject(myFunc, codec.JSON)))
+func SNSEnvelope[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, []byte) ([]byte, error) {
+	return func(ctx context.Context, d []byte) ([]byte, error) {
+		if e, ok := snsDecodeEnvelope(d); ok {
+			ctx = context.WithValue(ctx, contextKeySNSARN, e.TopicArn)
+			ctx = context.WithValue(ctx, contextKeySNSEntity, e)
+			d = []byte(e.Message)
+		}
+
+		if p, err := sliceStringOrUnmarshal[O](d); err != nil {
+			return nil, fmt.Errorf("SNSEnvelope unmarshal: %w", err)
+		} else {
+			if d, err := n(ctx, p); err != nil {
+				return nil, fmt.Errorf("SNSEnvelope next: %w", err)
+			} else {
+				return d, nil
+			}
+		}
+	}
+}
+
+// snsEnvelope is the JSON structure of an SNS notification envelope, it differs from events.SNSEntity in that the
+// message attributes are always present as Type and Value.
+type snsEnvelope struct {
+	Type              string
+	MessageID         string `json:"MessageId"`
+	TopicArn          string
+	Subject           string
+	Message           *string
+	Timestamp         json.RawMessage
+	SignatureVersion  string
+	Signature         string
+	SigningCertURL    string
+	UnsubscribeURL    string
+	MessageAttributes map[string]SNSMessageAttribute
+}
+
+// snsDecodeEnvelope attempts to decode data as an SNS notification envelope, returning false if it is not one.
+func snsDecodeEnvelope(data []byte) (events.SNSEntity, bool) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return events.SNSEntity{}, false
+	}
+
+	var env snsEnvelope
+
+	if err := json.Unmarshal(data, &env); err != nil || env.Type != snsNotificationType || env.TopicArn == "" || env.Message == nil {
+		return events.SNSEntity{}, false
+	}
+
+	e := events.SNSEntity{
+		Type:             env.Type,
+		MessageID:        env.MessageID,
+		TopicArn:         env.TopicArn,
+		Subject:          env.Subject,
+		Message:          *env.Message,
+		SignatureVersion: env.SignatureVersion,
+		Signature:        env.Signature,
+		SigningCertURL:   env.SigningCertURL,
+		UnsubscribeURL:   env.UnsubscribeURL,
+	}
+
+	if len(env.Timestamp) > 0 {
+		_ = json.Unmarshal(env.Timestamp, &e.Timestamp)
+	}
+
+	if len(env.MessageAttributes) > 0 {
+		e.MessageAttributes = make(map[string]interface{}, len(env.MessageAttributes))
+
+		for k, v := range env.MessageAttributes {
+			e.MessageAttributes[k] = map[string]interface{}{"Type": v.Type, "Value": v.Value}
+		}
+	}
+
+	return e, true
+}
@@ -0,0 +1,127 @@
+package lambdawrap
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testSNSEnvelope = `{
+  "Type" : "Notification",
+  "MessageId" : "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+  "TopicArn" : "arn:aws:sns:us-west-2:123456789012:MyTopic",
+  "Subject" : "My First Message",
+  "Message" : "{\"id\":\"1\"}",
+  "Timestamp" : "2012-05-02T00:54:06.655Z",
+  "SignatureVersion" : "1",
+  "Signature" : "EXAMPLEw6JRN",
+  "SigningCertURL" : "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem",
+  "UnsubscribeURL" : "https://sns.us-west-2.amazonaws.com/?Action=Unsubscribe",
+  "MessageAttributes" : {
+    "colour" : {"Type":"String","Value":"red"}
+  }
+}`
+
+func TestSNSEnvelope(t *testing.T) {
+	t.Run("unwraps the message from an SNS envelope and adds the notification to the context", func(t *testing.T) {
+		var entity events.SNSEntity
+		var arn string
+
+		next := func(ctx context.Context, d []byte) ([]byte, error) {
+			entity, _ = SNSEntityFromContext(ctx)
+			arn, _ = SNSTopicARNFromContext(ctx)
+			return d, nil
+		}
+
+		d, err := SNSEnvelope(next)(context.TODO(), []byte(testSNSEnvelope))
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":"1"}`, string(d))
+
+		assert.Equal(t, "arn:aws:sns:us-west-2:123456789012:MyTopic", arn)
+		assert.Equal(t, "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324", entity.MessageID)
+		assert.Equal(t, "My First Message", entity.Subject)
+		assert.Equal(t, "1", entity.SignatureVersion)
+		assert.Equal(t, "https://sns.us-west-2.amazonaws.com/SimpleNotificationService-f3ecfb7224c7233fe7bb5f59f96de52f.pem", entity.SigningCertURL)
+		assert.Equal(t, "https://sns.us-west-2.amazonaws.com/?Action=Unsubscribe", entity.UnsubscribeURL)
+		assert.True(t, time.Date(2012, 5, 2, 0, 54, 6, 655000000, time.UTC).Equal(entity.Timestamp))
+	})
+
+	t.Run("message attributes from the envelope are available via SNSMessageAttributeFromContext", func(t *testing.T) {
+		var attr SNSMessageAttribute
+		var found bool
+
+		next := func(ctx context.Context, d []byte) ([]byte, error) {
+			attr, found = SNSMessageAttributeFromContext(ctx, "colour")
+			return nil, nil
+		}
+
+		_, err := SNSEnvelope(next)(context.TODO(), []byte(testSNSEnvelope))
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, SNSMessageAttribute{Type: "String", Value: "red"}, attr)
+	})
+
+	t.Run("data which is not an SNS envelope is passed through unaltered", func(t *testing.T) {
+		for _, in := range []string{`{"id":"1"}`, `{"Type":"SubscriptionConfirmation","TopicArn":"arn","Message":"m"}`, `plain text`, `[1,2]`} {
+			var called bool
+
+			next := func(ctx context.Context, d []byte) ([]byte, error) {
+				_, called = SNSEntityFromContext(ctx)
+				return d, nil
+			}
+
+			d, err := SNSEnvelope(next)(context.TODO(), []byte(in))
+			assert.NoError(t, err)
+			assert.Equal(t, in, string(d))
+			assert.False(t, called)
+		}
+	})
+
+	t.Run("unwrapped message is unmarshalled into a struct", func(t *testing.T) {
+		type msg struct {
+			ID string `json:"id"`
+		}
+
+		var got msg
+
+		next := func(_ context.Context, m msg) ([]byte, error) {
+			got = m
+			return nil, nil
+		}
+
+		_, err := SNSEnvelope(next)(context.TODO(), []byte(testSNSEnvelope))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", got.ID)
+	})
+
+	t.Run("can be chained from SQS to handle raw and non raw delivery", func(t *testing.T) {
+		in := events.SQSEvent{
+			Records: []events.SQSMessage{
+				{Body: testSNSEnvelope},
+				{Body: `{"id":"2"}`},
+			},
+		}
+
+		next := func(_ context.Context, d []byte) ([]byte, error) {
+			return d, nil
+		}
+
+		d, err := SQS(SNSEnvelope(next))(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"id":"1"}{"id":"2"}`, string(d))
+	})
+
+	t.Run("returns wrapped error from next", func(t *testing.T) {
+		expected := errors.New("expected")
+
+		next := func(_ context.Context, _ []byte) ([]byte, error) {
+			return nil, expected
+		}
+
+		_, err := SNSEnvelope(next)(context.TODO(), []byte(testSNSEnvelope))
+		assert.ErrorIs(t, err, expected)
+	})
+}
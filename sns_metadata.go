@@ -0,0 +1,135 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"time"
+)
+
+// SNSMessageAttribute is a single message attribute of an SNS notification.
+type SNSMessageAttribute struct {
+	// Type is the data type of the attribute, e.g. String, String.Array, Number or Binary.
+	Type string
+	// Value is the value of the attribute, Binary values are base64 encoded.
+	Value string
+}
+
+// SNSEntityFromContext retrieves the events.SNSEntity being processed from the context, for use after an SNS wrap has
+// been used if the application needs details of the notification not provided by the other accessors.
+func SNSEntityFromContext(ctx context.Context) (events.SNSEntity, bool) {
+	if val := ctx.Value(contextKeySNSEntity); val != nil {
+		return val.(events.SNSEntity), true
+	} else {
+		return events.SNSEntity{}, false
+	}
+}
+
+// SNSMessageIDFromContext retrieves the ID of the notification from the context, for use after an SNS wrap has been
+// used.
+func SNSMessageIDFromContext(ctx context.Context) (string, bool) {
+	if e, ok := SNSEntityFromContext(ctx); ok {
+		return e.MessageID, true
+	} else {
+		return "", false
+	}
+}
+
+// SNSSubjectFromContext retrieves the subject of the notification from the context, for use after an SNS wrap has been
+// used. The subject is optional, an empty string is returned if the publisher did not provide one.
+func SNSSubjectFromContext(ctx context.Context) (string, bool) {
+	if e, ok := SNSEntityFromContext(ctx); ok {
+		return e.Subject, true
+	} else {
+		return "", false
+	}
+}
+
+// SNSTimestampFromContext retrieves the time the notification was published from the context, for use after an SNS
+// wrap has been used.
+func SNSTimestampFromContext(ctx context.Context) (time.Time, bool) {
+	if e, ok := SNSEntityFromContext(ctx); ok {
+		return e.Timestamp, true
+	} else {
+		return time.Time{}, false
+	}
+}
+
+// SNSMessageAttributesFromContext retrieves all message attributes of the notification from the context, for use after
+// an SNS wrap has been used.
+func SNSMessageAttributesFromContext(ctx context.Context) (map[string]SNSMessageAttribute, bool) {
+	e, ok := SNSEntityFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	ret := make(map[string]SNSMessageAttribute, len(e.MessageAttributes))
+
+	for name := range e.MessageAttributes {
+		if a, ok := snsMessageAttribute(e, name); ok {
+			ret[name] = a
+		}
+	}
+
+	return ret, true
+}
+
+// SNSMessageAttributeFromContext retrieves a named message attribute of the notification from the context, for use
+// after an SNS wrap has been used.
+func SNSMessageAttributeFromContext(ctx context.Context, name string) (SNSMessageAttribute, bool) {
+	if e, ok := SNSEntityFromContext(ctx); ok {
+		return snsMessageAttribute(e, name)
+	} else {
+		return SNSMessageAttribute{}, false
+	}
+}
+
+// SNSAttributeExists provides a function for use with Filter or Match, which matches if the notification has the named
+// message attribute.
+func SNSAttributeExists[O any](name string) func(context.Context, O) (bool, error) {
+	return func(ctx context.Context, _ O) (bool, error) {
+		_, ok := SNSMessageAttributeFromContext(ctx, name)
+		return ok, nil
+	}
+}
+
+// SNSAttributeEquals provides a function for use with Filter or Match, which matches if the notification has the named
+// message attribute with a value equal to one of values.
+//
+// Example:
+//
+//   SNS(Filter(SNSAttributeEquals[[]byte]("eventType", "OrderCreated"), DomainObject(SideEffect(myFunc), codec.JSON)))
+func SNSAttributeEquals[O any](name string, values ...string) func(context.Context, O) (bool, error) {
+	return func(ctx context.Context, _ O) (bool, error) {
+		if a, ok := SNSMessageAttributeFromContext(ctx, name); ok {
+			for _, v := range values {
+				if a.Value == v {
+					return true, nil
+				}
+			}
+		}
+
+		return false, nil
+	}
+}
+
+// SNSAttributeValue provides a function for use with SwitchContext, which selects on the value of the named message
+// attribute. An empty string is returned if the attribute is not present.
+func SNSAttributeValue[O any](name string) func(context.Context, O) string {
+	return func(ctx context.Context, _ O) string {
+		a, _ := SNSMessageAttributeFromContext(ctx, name)
+		return a.Value
+	}
+}
+
+// snsMessageAttribute extracts a message attribute, which is provided by events.SNSEntity as an untyped map.
+func snsMessageAttribute(e events.SNSEntity, name string) (SNSMessageAttribute, bool) {
+	raw, ok := e.MessageAttributes[name].(map[string]interface{})
+	if !ok {
+		return SNSMessageAttribute{}, false
+	}
+
+	t, _ := raw["Type"].(string)
+	v, _ := raw["Value"].(string)
+
+	return SNSMessageAttribute{Type: t, Value: v}, true
+}
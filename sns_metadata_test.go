@@ -0,0 +1,100 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSNSMetadataFromContext(t *testing.T) {
+	timestamp := time.Date(2022, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	record := func(message string, eventType string) events.SNSEventRecord {
+		return events.SNSEventRecord{
+			SNS: events.SNSEntity{
+				MessageID: "id-" + message,
+				Subject:   "subject",
+				Timestamp: timestamp,
+				Message:   message,
+				MessageAttributes: map[string]interface{}{
+					"eventType": map[string]interface{}{"Type": "String", "Value": eventType},
+				},
+			},
+		}
+	}
+
+	in := events.SNSEvent{
+		Records: []events.SNSEventRecord{
+			record("1", "created"),
+			record("2", "deleted"),
+			record("3", "created"),
+		},
+	}
+
+	t.Run("notification metadata is available on the context", func(t *testing.T) {
+		next := func(ctx context.Context, d string) ([]byte, error) {
+			e, ok := SNSEntityFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, d, e.Message)
+
+			id, ok := SNSMessageIDFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "id-"+d, id)
+
+			subject, ok := SNSSubjectFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "subject", subject)
+
+			ts, ok := SNSTimestampFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, timestamp, ts)
+
+			attrs, ok := SNSMessageAttributesFromContext(ctx)
+			assert.True(t, ok)
+			assert.Contains(t, attrs, "eventType")
+
+			a, ok := SNSMessageAttributeFromContext(ctx, "eventType")
+			assert.True(t, ok)
+			assert.Equal(t, "String", a.Type)
+
+			_, ok = SNSMessageAttributeFromContext(ctx, "missing")
+			assert.False(t, ok)
+
+			return nil, nil
+		}
+
+		_, err := SNS(next)(context.TODO(), in)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Filter can be used with SNSAttributeEquals and SNSAttributeExists", func(t *testing.T) {
+		next := func(_ context.Context, d string) ([]byte, error) {
+			return []byte(d), nil
+		}
+
+		d, err := SNS(Filter(SNSAttributeEquals[string]("eventType", "created"), next))(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "13", string(d))
+
+		d, err = SNS(Filter(SNSAttributeExists[string]("missing"), next))(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Nil(t, d)
+	})
+
+	t.Run("SwitchContext can be used with SNSAttributeValue", func(t *testing.T) {
+		respond := func(s string) func(context.Context, string) ([]byte, error) {
+			return func(_ context.Context, _ string) ([]byte, error) {
+				return []byte(s), nil
+			}
+		}
+
+		d, err := SNS(SwitchContext(SNSAttributeValue[string]("eventType"), map[string]func(context.Context, string) ([]byte, error){
+			"created": respond("c"),
+			"deleted": respond("d"),
+		}))(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "cdc", string(d))
+	})
+}
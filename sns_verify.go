@@ -0,0 +1,219 @@
+package lambdawrap
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrSNSSignatureInvalid is wrapped by errors returned from SNSVerify when a notification can not be verified as
+// having been sent by SNS.
+var ErrSNSSignatureInvalid = errors.New("sns signature invalid")
+
+// snsTimestampFormat is the format of the Timestamp in an SNS notification, as used in the canonical string.
+const snsTimestampFormat = "2006-01-02T15:04:05.000Z"
+
+// snsCertificateHost matches the hosts SNS signing certificates may be fetched from.
+var snsCertificateHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// SNSCertificateFetcher retrieves the X.509 signing certificate at the provided URL, it is used by SNSVerify. The URL
+// has been checked as being an SNS certificate URL before it is called.
+//
+// SNSHTTPCertificateFetcher provides a basic implementation, which should usually be wrapped with
+// SNSCachedCertificateFetcher.
+type SNSCertificateFetcher func(context.Context, string) (*x509.Certificate, error)
+
+// SNSVerify consumes a []byte and verifies the signature of the SNS notification on the context before calling next,
+// it must be chained from the SNS wrap or SNSEnvelope. The canonical string of the notification is rebuilt and checked
+// against the signature using the signing certificate, both SignatureVersion 1 (SHA1) and 2 (SHA256) are supported.
+//
+// The signing certificate URL must use https and be hosted on sns.<region>.amazonaws.com, the certificate is retrieved
+// via f. If no notification is on the context, e.g. a raw message delivered by SQS, the notification can not be
+// verified, or the []byte provided is not the Message of the notification, an error wrapping ErrSNSSignatureInvalid is
+// returned and next is not called. SNSVerify should therefore be chained directly from the SNS wrap or SNSEnvelope.
+//
+// Example:
+//
+//   fetcher := SNSCachedCertificateFetcher(SNSHTTPCertificateFetcher(nil))
+//   SQS(SNSEnvelope(SNSVerify(DomainObject(myFunc, codec.JSON), fetcher)))
+func SNSVerify(n func(context.Context, []byte) ([]byte, error), f SNSCertificateFetcher) func(context.Context, []byte) ([]byte, error) {
+	return func(ctx context.Context, d []byte) ([]byte, error) {
+		e, ok := SNSEntityFromContext(ctx)
+		if !ok {
+			return nil, fmt.Errorf("SNSVerify: %w: no notification on context", ErrSNSSignatureInvalid)
+		}
+
+		if string(d) != e.Message {
+			return nil, fmt.Errorf("SNSVerify: %w: payload is not the notification message", ErrSNSSignatureInvalid)
+		}
+
+		if err := snsVerify(ctx, e, f); err != nil {
+			return nil, fmt.Errorf("SNSVerify: %w", err)
+		}
+
+		if d, err := n(ctx, d); err != nil {
+			return nil, fmt.Errorf("SNSVerify next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
+
+// SNSCachedCertificateFetcher wraps an SNSCertificateFetcher, caching each certificate by URL for the lifetime of the
+// Lambda execution environment. Failures are not cached.
+func SNSCachedCertificateFetcher(f SNSCertificateFetcher) SNSCertificateFetcher {
+	mutex := &sync.Mutex{}
+	cache := map[string]*x509.Certificate{}
+
+	return func(ctx context.Context, u string) (*x509.Certificate, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if c, ok := cache[u]; ok {
+			return c, nil
+		}
+
+		c, err := f(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+
+		cache[u] = c
+		return c, nil
+	}
+}
+
+// SNSHTTPCertificateFetcher provides an SNSCertificateFetcher which retrieves the PEM encoded certificate using the
+// provided http.Client, or http.DefaultClient if nil.
+func SNSHTTPCertificateFetcher(c *http.Client) SNSCertificateFetcher {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return func(ctx context.Context, u string) (*x509.Certificate, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("sns certificate request: %w", err)
+		}
+
+		resp, err := c.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("sns certificate fetch: %w", err)
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("sns certificate fetch: unexpected status %d", resp.StatusCode)
+		}
+
+		d, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if err != nil {
+			return nil, fmt.Errorf("sns certificate read: %w", err)
+		}
+
+		block, _ := pem.Decode(d)
+		if block == nil {
+			return nil, errors.New("sns certificate decode: no pem block found")
+		}
+
+		if cert, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("sns certificate parse: %w", err)
+		} else {
+			return cert, nil
+		}
+	}
+}
+
+// snsVerify checks the signature of the notification against its signing certificate.
+func snsVerify(ctx context.Context, e events.SNSEntity, f SNSCertificateFetcher) error {
+	if e.Type != snsNotificationType {
+		return fmt.Errorf("%w: unsupported type %q", ErrSNSSignatureInvalid, e.Type)
+	}
+
+	var hash crypto.Hash
+	var sum []byte
+	canonical := []byte(snsCanonicalString(e))
+
+	switch e.SignatureVersion {
+	case "1":
+		s := sha1.Sum(canonical)
+		hash, sum = crypto.SHA1, s[:]
+	case "2":
+		s := sha256.Sum256(canonical)
+		hash, sum = crypto.SHA256, s[:]
+	default:
+		return fmt.Errorf("%w: unsupported signature version %q", ErrSNSSignatureInvalid, e.SignatureVersion)
+	}
+
+	if !snsCertificateURLValid(e.SigningCertURL) {
+		return fmt.Errorf("%w: signing certificate url not permitted: %s", ErrSNSSignatureInvalid, e.SigningCertURL)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature decode: %s", ErrSNSSignatureInvalid, err)
+	}
+
+	cert, err := f(ctx, e.SigningCertURL)
+	if err != nil {
+		return fmt.Errorf("certificate fetch: %w", err)
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: certificate public key is not rsa", ErrSNSSignatureInvalid)
+	}
+
+	if err := rsa.VerifyPKCS1v15(pub, hash, sum, signature); err != nil {
+		return fmt.Errorf("%w: %s", ErrSNSSignatureInvalid, err)
+	}
+
+	return nil
+}
+
+// snsCanonicalString builds the string signed by SNS for a notification, the Subject is only included if present.
+func snsCanonicalString(e events.SNSEntity) string {
+	sb := &strings.Builder{}
+
+	add := func(k, v string) {
+		sb.WriteString(k + "\n" + v + "\n")
+	}
+
+	add("Message", e.Message)
+	add("MessageId", e.MessageID)
+
+	if e.Subject != "" {
+		add("Subject", e.Subject)
+	}
+
+	add("Timestamp", e.Timestamp.UTC().Format(snsTimestampFormat))
+	add("TopicArn", e.TopicArn)
+	add("Type", e.Type)
+
+	return sb.String()
+}
+
+// snsCertificateURLValid checks that a signing certificate URL uses https and is hosted by SNS.
+func snsCertificateURLValid(u string) bool {
+	p, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+
+	return p.Scheme == "https" && p.User == nil && p.Port() == "" && snsCertificateHost.MatchString(p.Hostname())
+}
@@ -0,0 +1,283 @@
+package lambdawrap
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testSNSCertURL = "https://sns.eu-west-1.amazonaws.com/SimpleNotificationService-test.pem"
+
+func testSNSCertificate(t *testing.T) (*rsa.PrivateKey, *x509.Certificate, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return key, cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func testSNSSign(t *testing.T, key *rsa.PrivateKey, e events.SNSEntity) events.SNSEntity {
+	var hash crypto.Hash
+	var sum []byte
+
+	canonical := []byte(snsCanonicalString(e))
+
+	if e.SignatureVersion == "1" {
+		s := sha1.Sum(canonical)
+		hash, sum = crypto.SHA1, s[:]
+	} else {
+		s := sha256.Sum256(canonical)
+		hash, sum = crypto.SHA256, s[:]
+	}
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, hash, sum)
+	assert.NoError(t, err)
+
+	e.Signature = base64.StdEncoding.EncodeToString(sig)
+	return e
+}
+
+func testSNSEntity(version string) events.SNSEntity {
+	return events.SNSEntity{
+		Type:             "Notification",
+		MessageID:        "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+		TopicArn:         "arn:aws:sns:eu-west-1:123456789012:MyTopic",
+		Subject:          "Subject",
+		Message:          "message",
+		Timestamp:        time.Date(2012, 5, 2, 0, 54, 6, 655000000, time.UTC),
+		SignatureVersion: version,
+		SigningCertURL:   testSNSCertURL,
+	}
+}
+
+func TestSNSVerify(t *testing.T) {
+	key, cert, _ := testSNSCertificate(t)
+
+	fetcher := func(_ context.Context, u string) (*x509.Certificate, error) {
+		return cert, nil
+	}
+
+	next := func(_ context.Context, d []byte) ([]byte, error) {
+		return d, nil
+	}
+
+	for _, version := range []string{"1", "2"} {
+		t.Run("verifies a notification signed with signature version "+version, func(t *testing.T) {
+			e := testSNSSign(t, key, testSNSEntity(version))
+			ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+			d, err := SNSVerify(next, fetcher)(ctx, []byte("message"))
+			assert.NoError(t, err)
+			assert.Equal(t, "message", string(d))
+		})
+	}
+
+	t.Run("verifies a notification without a subject", func(t *testing.T) {
+		e := testSNSEntity("2")
+		e.Subject = ""
+		e = testSNSSign(t, key, e)
+		ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+		_, err := SNSVerify(next, fetcher)(ctx, []byte("message"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects a payload which is not the message of the verified notification", func(t *testing.T) {
+		e := testSNSSign(t, key, testSNSEntity("2"))
+		ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+		called := false
+		n := func(_ context.Context, d []byte) ([]byte, error) {
+			called = true
+			return d, nil
+		}
+
+		_, err := SNSVerify(n, fetcher)(ctx, []byte("unverified"))
+		assert.ErrorIs(t, err, ErrSNSSignatureInvalid)
+		assert.False(t, called)
+	})
+
+	t.Run("rejects a notification which has been altered", func(t *testing.T) {
+		e := testSNSSign(t, key, testSNSEntity("2"))
+		e.Message = "altered"
+		ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+		called := false
+		n := func(_ context.Context, d []byte) ([]byte, error) {
+			called = true
+			return d, nil
+		}
+
+		_, err := SNSVerify(n, fetcher)(ctx, []byte(e.Message))
+		assert.ErrorIs(t, err, ErrSNSSignatureInvalid)
+		assert.False(t, called)
+	})
+
+	t.Run("rejects a notification signed by a different key", func(t *testing.T) {
+		other, _, _ := testSNSCertificate(t)
+		e := testSNSSign(t, other, testSNSEntity("2"))
+		ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+		_, err := SNSVerify(next, fetcher)(ctx, []byte(e.Message))
+		assert.ErrorIs(t, err, ErrSNSSignatureInvalid)
+	})
+
+	t.Run("rejects signing certificate urls not hosted by SNS", func(t *testing.T) {
+		for _, u := range []string{
+			"http://sns.eu-west-1.amazonaws.com/cert.pem",
+			"https://example.com/cert.pem",
+			"https://sns.eu-west-1.amazonaws.com.example.com/cert.pem",
+			"https://sns.eu-west-1.amazonaws.com:8443/cert.pem",
+		} {
+			e := testSNSEntity("2")
+			e.SigningCertURL = u
+			e = testSNSSign(t, key, e)
+			ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+			_, err := SNSVerify(next, fetcher)(ctx, []byte(e.Message))
+			assert.ErrorIs(t, err, ErrSNSSignatureInvalid, u)
+		}
+	})
+
+	t.Run("accepts signing certificate urls in China regions", func(t *testing.T) {
+		assert.True(t, snsCertificateURLValid("https://sns.cn-north-1.amazonaws.com.cn/cert.pem"))
+	})
+
+	t.Run("rejects unsupported signature versions", func(t *testing.T) {
+		e := testSNSSign(t, key, testSNSEntity("2"))
+		e.SignatureVersion = "3"
+		ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+		_, err := SNSVerify(next, fetcher)(ctx, []byte(e.Message))
+		assert.ErrorIs(t, err, ErrSNSSignatureInvalid)
+	})
+
+	t.Run("rejects when no notification is on the context", func(t *testing.T) {
+		_, err := SNSVerify(next, fetcher)(context.TODO(), nil)
+		assert.ErrorIs(t, err, ErrSNSSignatureInvalid)
+	})
+
+	t.Run("returns wrapped error from the fetcher", func(t *testing.T) {
+		expected := errors.New("expected")
+
+		f := func(_ context.Context, _ string) (*x509.Certificate, error) {
+			return nil, expected
+		}
+
+		e := testSNSSign(t, key, testSNSEntity("2"))
+		ctx := context.WithValue(context.TODO(), contextKeySNSEntity, e)
+
+		_, err := SNSVerify(next, f)(ctx, []byte(e.Message))
+		assert.ErrorIs(t, err, expected)
+	})
+
+	t.Run("can be chained from SNSEnvelope", func(t *testing.T) {
+		e := testSNSSign(t, key, testSNSEntity("1"))
+
+		body := `{"Type":"Notification","MessageId":"` + e.MessageID + `","TopicArn":"` + e.TopicArn + `","Subject":"` + e.Subject +
+			`","Message":"` + e.Message + `","Timestamp":"2012-05-02T00:54:06.655Z","SignatureVersion":"1","Signature":"` + e.Signature +
+			`","SigningCertURL":"` + testSNSCertURL + `"}`
+
+		d, err := SNSEnvelope(SNSVerify(next, fetcher))(context.TODO(), []byte(body))
+		assert.NoError(t, err)
+		assert.Equal(t, "message", string(d))
+	})
+}
+
+func TestSNSCachedCertificateFetcher(t *testing.T) {
+	t.Run("calls the underlying fetcher once per url", func(t *testing.T) {
+		_, cert, _ := testSNSCertificate(t)
+		calls := 0
+
+		f := SNSCachedCertificateFetcher(func(_ context.Context, _ string) (*x509.Certificate, error) {
+			calls++
+			return cert, nil
+		})
+
+		for i := 0; i < 3; i++ {
+			c, err := f(context.TODO(), testSNSCertURL)
+			assert.NoError(t, err)
+			assert.Equal(t, cert, c)
+		}
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("failures are not cached", func(t *testing.T) {
+		calls := 0
+
+		f := SNSCachedCertificateFetcher(func(_ context.Context, _ string) (*x509.Certificate, error) {
+			calls++
+			return nil, errors.New("expected")
+		})
+
+		_, err := f(context.TODO(), testSNSCertURL)
+		assert.Error(t, err)
+		_, err = f(context.TODO(), testSNSCertURL)
+		assert.Error(t, err)
+
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestSNSHTTPCertificateFetcher(t *testing.T) {
+	t.Run("fetches and parses a pem encoded certificate", func(t *testing.T) {
+		_, cert, p := testSNSCertificate(t)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write(p)
+		}))
+		defer srv.Close()
+
+		c, err := SNSHTTPCertificateFetcher(srv.Client())(context.TODO(), srv.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, cert.Raw, c.Raw)
+	})
+
+	t.Run("returns an error on a non 200 status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer srv.Close()
+
+		_, err := SNSHTTPCertificateFetcher(nil)(context.TODO(), srv.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("returns an error if the body is not a certificate", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte("not a certificate"))
+		}))
+		defer srv.Close()
+
+		_, err := SNSHTTPCertificateFetcher(nil)(context.TODO(), srv.URL)
+		assert.Error(t, err)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
+	"strings"
 )
 
 // SQS provides a wrapper to iterate through multiple SQS records included in an events.SQSEvent. Default behaviour is
@@ -14,20 +15,50 @@ import (
 // encodings other than JSON.
 //
 // Do not use this directly for domain objects, use DomainObject.
-func SQS[O any](n func(context.Context, O) ([]byte, error)) func(context.Context, events.SQSEvent) ([]byte, error) {
+//
+// Messages can be processed concurrently with WithConcurrency. Messages from a FIFO queue are always processed as if
+// WithOrdered was provided, so messages sharing a MessageGroupId are processed in order.
+func SQS[O any](n func(context.Context, O) ([]byte, error), opts ...BatchOption) func(context.Context, events.SQSEvent) ([]byte, error) {
+	o := newBatchOptions(opts)
+
 	return func(ctx context.Context, e events.SQSEvent) ([]byte, error) {
-		var ret []byte
-
-		for _, r := range e.Records {
-			ctx = context.WithValue(ctx, contextKeySQSARN, r.EventSourceARN)
-			if p, err := sliceStringOrUnmarshal[O]([]byte(r.Body)); err != nil {
-				return nil, fmt.Errorf("SQS unmarshal: %w", err)
-			} else {
-				if d, err := n(ctx, p); err != nil {
-					return nil, fmt.Errorf("SQS next: %w", err)
-				} else {
-					ret = append(ret, d...)
-				}
+		data, errs := batchRun(ctx, sqsOptions(o, e.Records), true, len(e.Records), sqsOrderingKey(e.Records), func(ctx context.Context, i int) ([]byte, error) {
+			return sqsMessage(ctx, e.Records[i], n)
+		})
+
+		return batchConcat(o, data, errs)
+	}
+}
+
+// SQSBatchItemFailures provides a wrapper to iterate through multiple SQS records included in an events.SQSEvent,
+// reporting any messages that failed as an events.SQSEventResponse. Unlike SQS, every message is processed regardless of
+// earlier failures, and only the message IDs of those that failed to unmarshal or returned an error from next are
+// included in BatchItemFailures. The Lambda event source mapping must have ReportBatchItemFailures enabled, otherwise
+// the response is ignored and all messages are deleted from the queue.
+//
+// The []byte output of next is discarded, use Output if the output of each message is needed.
+//
+// SQSBatchItemFailures will attempt to unmarshal any destination structure with JSON in the same way as SQS. It is
+// recommended you use DomainObject instead.
+//
+// Messages can be processed concurrently with WithConcurrency, failures do not cancel the processing of other messages.
+//
+// Messages from a FIFO queue are processed in order within their message group, groups may be processed concurrently
+// with WithConcurrency. When a message fails, the remaining messages in its group are not processed and are reported as
+// batch item failures along with it, so the group is redelivered in order.
+func SQSBatchItemFailures[O any](n func(context.Context, O) ([]byte, error), opts ...BatchOption) func(context.Context, events.SQSEvent) (events.SQSEventResponse, error) {
+	o := newBatchOptions(opts)
+
+	return func(ctx context.Context, e events.SQSEvent) (events.SQSEventResponse, error) {
+		ret := events.SQSEventResponse{BatchItemFailures: []events.SQSBatchItemFailure{}}
+
+		_, errs := batchRun(ctx, sqsOptions(o, e.Records), false, len(e.Records), sqsOrderingKey(e.Records), func(ctx context.Context, i int) ([]byte, error) {
+			return sqsMessage(ctx, e.Records[i], n)
+		})
+
+		for i, err := range errs {
+			if err != nil {
+				ret.BatchItemFailures = append(ret.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: e.Records[i].MessageId})
 			}
 		}
 
@@ -35,6 +66,42 @@ func SQS[O any](n func(context.Context, O) ([]byte, error)) func(context.Context
 	}
 }
 
+// sqsOptions enables ordered processing if the messages are from a FIFO queue.
+func sqsOptions(o batchOptions, r []events.SQSMessage) batchOptions {
+	if len(r) > 0 && strings.HasSuffix(r[0].EventSourceARN, ".fifo") {
+		o.ordered = true
+	}
+
+	return o
+}
+
+// sqsOrderingKey uses the MessageGroupId of FIFO messages, messages from a standard queue have no ordering so fall back
+// to their message ID, otherwise they would all share an empty key.
+func sqsOrderingKey(r []events.SQSMessage) func(int) string {
+	return func(i int) string {
+		if group, ok := r[i].Attributes["MessageGroupId"]; ok && group != "" {
+			return group
+		}
+
+		return r[i].MessageId
+	}
+}
+
+func sqsMessage[O any](ctx context.Context, r events.SQSMessage, n func(context.Context, O) ([]byte, error)) ([]byte, error) {
+	ctx = context.WithValue(ctx, contextKeySQSARN, r.EventSourceARN)
+	ctx = context.WithValue(ctx, contextKeySQSMessage, r)
+
+	if p, err := sliceStringOrUnmarshal[O]([]byte(r.Body)); err != nil {
+		return nil, fmt.Errorf("SQS unmarshal: %w", err)
+	} else {
+		if d, err := n(ctx, p); err != nil {
+			return nil, fmt.Errorf("SQS next: %w", err)
+		} else {
+			return d, nil
+		}
+	}
+}
+
 // SQSTopicARNFromContext retrieves a SQS queue ARN from the context, for use after an SQS wrap has been used if the
 // application needs the topic that the message was provided on.
 func SQSTopicARNFromContext(ctx context.Context) (string, bool) {
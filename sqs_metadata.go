@@ -0,0 +1,136 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQSMessageFromContext retrieves the events.SQSMessage being processed from the context, for use after an SQS wrap
+// has been used if the application needs details of the message not provided by the other accessors.
+func SQSMessageFromContext(ctx context.Context) (events.SQSMessage, bool) {
+	if val := ctx.Value(contextKeySQSMessage); val != nil {
+		return val.(events.SQSMessage), true
+	} else {
+		return events.SQSMessage{}, false
+	}
+}
+
+// SQSMessageIDFromContext retrieves the ID of the message from the context, for use after an SQS wrap has been used.
+func SQSMessageIDFromContext(ctx context.Context) (string, bool) {
+	if m, ok := SQSMessageFromContext(ctx); ok {
+		return m.MessageId, true
+	} else {
+		return "", false
+	}
+}
+
+// SQSReceiptHandleFromContext retrieves the receipt handle of the message from the context, for use after an SQS wrap
+// has been used if the application needs to change the visibility of, or delete, the message.
+func SQSReceiptHandleFromContext(ctx context.Context) (string, bool) {
+	if m, ok := SQSMessageFromContext(ctx); ok {
+		return m.ReceiptHandle, true
+	} else {
+		return "", false
+	}
+}
+
+// SQSApproximateReceiveCountFromContext retrieves the number of times the message has been received from the context,
+// for use after an SQS wrap has been used. This can be used to make decisions about poison messages.
+func SQSApproximateReceiveCountFromContext(ctx context.Context) (int, bool) {
+	if v, ok := sqsAttributeFromContext(ctx, "ApproximateReceiveCount"); ok {
+		if i, err := strconv.Atoi(v); err == nil {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// SQSSentTimestampFromContext retrieves the time the message was sent to the queue from the context, for use after an
+// SQS wrap has been used.
+func SQSSentTimestampFromContext(ctx context.Context) (time.Time, bool) {
+	if v, ok := sqsAttributeFromContext(ctx, "SentTimestamp"); ok {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms), true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// SQSMessageGroupIDFromContext retrieves the MessageGroupId of a message from a FIFO queue from the context, for use
+// after an SQS wrap has been used.
+func SQSMessageGroupIDFromContext(ctx context.Context) (string, bool) {
+	return sqsAttributeFromContext(ctx, "MessageGroupId")
+}
+
+// SQSMessageDeduplicationIDFromContext retrieves the MessageDeduplicationId of a message from a FIFO queue from the
+// context, for use after an SQS wrap has been used.
+func SQSMessageDeduplicationIDFromContext(ctx context.Context) (string, bool) {
+	return sqsAttributeFromContext(ctx, "MessageDeduplicationId")
+}
+
+// SQSMessageAttributesFromContext retrieves the custom message attributes of the message from the context, for use
+// after an SQS wrap has been used.
+func SQSMessageAttributesFromContext(ctx context.Context) (map[string]events.SQSMessageAttribute, bool) {
+	if m, ok := SQSMessageFromContext(ctx); ok {
+		return m.MessageAttributes, true
+	} else {
+		return nil, false
+	}
+}
+
+// SQSStringAttributeFromContext retrieves a named String message attribute from the context, for use after an SQS wrap
+// has been used. Attributes with a custom String type (e.g. String.UUID) are also returned.
+func SQSStringAttributeFromContext(ctx context.Context, name string) (string, bool) {
+	if a, ok := sqsMessageAttributeFromContext(ctx, name, "String"); ok && a.StringValue != nil {
+		return *a.StringValue, true
+	}
+
+	return "", false
+}
+
+// SQSNumberAttributeFromContext retrieves a named Number message attribute from the context, for use after an SQS wrap
+// has been used. Attributes with a custom Number type (e.g. Number.int) are also returned.
+func SQSNumberAttributeFromContext(ctx context.Context, name string) (float64, bool) {
+	if a, ok := sqsMessageAttributeFromContext(ctx, name, "Number"); ok && a.StringValue != nil {
+		if f, err := strconv.ParseFloat(*a.StringValue, 64); err == nil {
+			return f, true
+		}
+	}
+
+	return 0, false
+}
+
+// SQSBinaryAttributeFromContext retrieves a named Binary message attribute from the context, for use after an SQS wrap
+// has been used. Attributes with a custom Binary type (e.g. Binary.gif) are also returned.
+func SQSBinaryAttributeFromContext(ctx context.Context, name string) ([]byte, bool) {
+	if a, ok := sqsMessageAttributeFromContext(ctx, name, "Binary"); ok {
+		return a.BinaryValue, true
+	}
+
+	return nil, false
+}
+
+func sqsAttributeFromContext(ctx context.Context, name string) (string, bool) {
+	if m, ok := SQSMessageFromContext(ctx); ok {
+		v, ok := m.Attributes[name]
+		return v, ok
+	} else {
+		return "", false
+	}
+}
+
+// sqsMessageAttributeFromContext retrieves a message attribute, if its data type (ignoring any custom suffix) matches.
+func sqsMessageAttributeFromContext(ctx context.Context, name string, dataType string) (events.SQSMessageAttribute, bool) {
+	if m, ok := SQSMessageFromContext(ctx); ok {
+		if a, ok := m.MessageAttributes[name]; ok && strings.SplitN(a.DataType, ".", 2)[0] == dataType {
+			return a, true
+		}
+	}
+
+	return events.SQSMessageAttribute{}, false
+}
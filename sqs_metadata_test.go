@@ -0,0 +1,110 @@
+package lambdawrap
+
+import (
+	"context"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSQSMetadataFromContext(t *testing.T) {
+	str := func(s string) *string {
+		return &s
+	}
+
+	in := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{
+				MessageId:     "id",
+				ReceiptHandle: "handle",
+				Body:          "body",
+				Attributes: map[string]string{
+					"ApproximateReceiveCount": "3",
+					"SentTimestamp":           "1650000000123",
+				},
+				MessageAttributes: map[string]events.SQSMessageAttribute{
+					"correlationId": {DataType: "String", StringValue: str("abc")},
+					"uuid":          {DataType: "String.UUID", StringValue: str("def")},
+					"priority":      {DataType: "Number", StringValue: str("2.5")},
+					"image":         {DataType: "Binary.gif", BinaryValue: []byte{0x01}},
+				},
+			},
+		},
+	}
+
+	t.Run("message metadata is available on the context", func(t *testing.T) {
+		next := func(ctx context.Context, _ string) ([]byte, error) {
+			m, ok := SQSMessageFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, in.Records[0], m)
+
+			id, ok := SQSMessageIDFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "id", id)
+
+			handle, ok := SQSReceiptHandleFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "handle", handle)
+
+			count, ok := SQSApproximateReceiveCountFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, 3, count)
+
+			sent, ok := SQSSentTimestampFromContext(ctx)
+			assert.True(t, ok)
+			assert.True(t, time.UnixMilli(1650000000123).Equal(sent))
+
+			attrs, ok := SQSMessageAttributesFromContext(ctx)
+			assert.True(t, ok)
+			assert.Len(t, attrs, 4)
+
+			return nil, nil
+		}
+
+		_, err := SQS(next)(context.TODO(), in)
+		assert.NoError(t, err)
+	})
+
+	t.Run("message attributes can be read by name and type", func(t *testing.T) {
+		next := func(ctx context.Context, _ string) ([]byte, error) {
+			s, ok := SQSStringAttributeFromContext(ctx, "correlationId")
+			assert.True(t, ok)
+			assert.Equal(t, "abc", s)
+
+			s, ok = SQSStringAttributeFromContext(ctx, "uuid")
+			assert.True(t, ok)
+			assert.Equal(t, "def", s)
+
+			n, ok := SQSNumberAttributeFromContext(ctx, "priority")
+			assert.True(t, ok)
+			assert.Equal(t, 2.5, n)
+
+			b, ok := SQSBinaryAttributeFromContext(ctx, "image")
+			assert.True(t, ok)
+			assert.Equal(t, []byte{0x01}, b)
+
+			_, ok = SQSStringAttributeFromContext(ctx, "priority")
+			assert.False(t, ok)
+
+			_, ok = SQSNumberAttributeFromContext(ctx, "missing")
+			assert.False(t, ok)
+
+			return nil, nil
+		}
+
+		_, err := SQS(next)(context.TODO(), in)
+		assert.NoError(t, err)
+	})
+
+	t.Run("accessors return false outside of an SQS wrap", func(t *testing.T) {
+		_, ok := SQSMessageIDFromContext(context.TODO())
+		assert.False(t, ok)
+
+		_, ok = SQSApproximateReceiveCountFromContext(context.TODO())
+		assert.False(t, ok)
+
+		_, ok = SQSStringAttributeFromContext(context.TODO(), "correlationId")
+		assert.False(t, ok)
+	})
+}
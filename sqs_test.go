@@ -6,6 +6,7 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"sync"
 	"testing"
 )
 
@@ -133,3 +134,193 @@ func TestSQS(t *testing.T) {
 		assert.Nil(t, d)
 	})
 }
+
+func TestSQSBatchItemFailures(t *testing.T) {
+	t.Run("every event.SQSMessage is processed and only those that fail are reported as batch item failures", func(t *testing.T) {
+		in := events.SQSEvent{
+			Records: []events.SQSMessage{
+				{
+					MessageId: "1",
+					Body:      "1",
+				},
+				{
+					MessageId: "2",
+					Body:      "2",
+				},
+				{
+					MessageId: "3",
+					Body:      "3",
+				},
+			},
+		}
+
+		var seen []string
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			seen = append(seen, d)
+
+			if d == "2" {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return []byte(d), nil
+		}
+
+		resp, err := SQSBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, seen)
+		assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "2"}}, resp.BatchItemFailures)
+	})
+
+	t.Run("a message which fails to unmarshal is reported as a batch item failure", func(t *testing.T) {
+		type myStruct struct {
+			Val string
+		}
+
+		in := events.SQSEvent{
+			Records: []events.SQSMessage{
+				{
+					MessageId: "1",
+					Body:      "{\"val\": \"1\"",
+				},
+				{
+					MessageId: "2",
+					Body:      "{\"val\": \"2\"}",
+				},
+			},
+		}
+
+		next := func(_ context.Context, d myStruct) ([]byte, error) {
+			assert.Equal(t, "2", d.Val)
+			return nil, nil
+		}
+
+		resp, err := SQSBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "1"}}, resp.BatchItemFailures)
+	})
+
+	t.Run("no failures results in an empty set of batch item failures", func(t *testing.T) {
+		in := events.SQSEvent{
+			Records: []events.SQSMessage{
+				{
+					MessageId:      "1",
+					EventSourceARN: "sqsARN",
+					Body:           "1",
+				},
+			},
+		}
+
+		next := func(ctx context.Context, d []byte) ([]byte, error) {
+			topic, ok := SQSTopicARNFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "sqsARN", topic)
+
+			return d, nil
+		}
+
+		resp, err := SQSBatchItemFailures(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Empty(t, resp.BatchItemFailures)
+	})
+}
+
+func TestSQSFIFO(t *testing.T) {
+	message := func(id string, group string) events.SQSMessage {
+		return events.SQSMessage{
+			MessageId:      id,
+			EventSourceARN: "arn:aws:sqs:eu-west-1:123456789012:queue.fifo",
+			Body:           id,
+			Attributes: map[string]string{
+				"MessageGroupId":         group,
+				"MessageDeduplicationId": "dedup-" + id,
+			},
+		}
+	}
+
+	in := events.SQSEvent{
+		Records: []events.SQSMessage{
+			message("a1", "a"),
+			message("b1", "b"),
+			message("a2", "a"),
+			message("b2", "b"),
+			message("a3", "a"),
+		},
+	}
+
+	t.Run("group and deduplication IDs are available on the context", func(t *testing.T) {
+		next := func(ctx context.Context, d string) ([]byte, error) {
+			group, ok := SQSMessageGroupIDFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, d[:1], group)
+
+			dedup, ok := SQSMessageDeduplicationIDFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, "dedup-"+d, dedup)
+
+			return []byte(d), nil
+		}
+
+		d, err := SQS(next)(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.Equal(t, "a1b1a2b2a3", string(d))
+	})
+
+	t.Run("a failure stops its message group, and every remaining message in the group is reported as a batch item failure", func(t *testing.T) {
+		for _, concurrency := range []int{1, 2} {
+			lock := &sync.Mutex{}
+			var seen []string
+
+			next := func(_ context.Context, d string) ([]byte, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				seen = append(seen, d)
+
+				if d == "a2" {
+					return nil, io.ErrUnexpectedEOF
+				}
+
+				return nil, nil
+			}
+
+			resp, err := SQSBatchItemFailures(next, WithConcurrency(concurrency))(context.TODO(), in)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []string{"a1", "a2", "b1", "b2"}, seen)
+			assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "a2"}, {ItemIdentifier: "a3"}}, resp.BatchItemFailures)
+		}
+	})
+}
+
+func TestSQSOrderedStandardQueue(t *testing.T) {
+	in := events.SQSEvent{
+		Records: []events.SQSMessage{
+			{MessageId: "1", EventSourceARN: "arn:aws:sqs:eu-west-1:123456789012:queue", Body: "1"},
+			{MessageId: "2", EventSourceARN: "arn:aws:sqs:eu-west-1:123456789012:queue", Body: "2"},
+			{MessageId: "3", EventSourceARN: "arn:aws:sqs:eu-west-1:123456789012:queue", Body: "3"},
+		},
+	}
+
+	t.Run("messages without a MessageGroupId do not share an ordering key", func(t *testing.T) {
+		lock := &sync.Mutex{}
+		var seen []string
+
+		next := func(_ context.Context, d string) ([]byte, error) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			seen = append(seen, d)
+
+			if d == "1" {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			return nil, nil
+		}
+
+		resp, err := SQSBatchItemFailures(next, WithOrdered())(context.TODO(), in)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"1", "2", "3"}, seen)
+		assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "1"}}, resp.BatchItemFailures)
+	})
+}